@@ -0,0 +1,14 @@
+module github.com/predictionguard/gophercon-gen-ai/basic-prompting/example4
+
+go 1.21.1
+
+require github.com/predictionguard/gophercon-gen-ai/gengo v0.0.0
+
+require (
+	github.com/cohere-ai/cohere-go v0.2.0 // indirect
+	github.com/cohere-ai/tokenizer v1.1.1 // indirect
+	github.com/dlclark/regexp2 v1.4.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+)
+
+replace github.com/predictionguard/gophercon-gen-ai/gengo => ../../gengo
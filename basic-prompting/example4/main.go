@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/fewshot"
+)
+
+// Define the API details to access the LLM.
+var url = "https://api.predictionguard.com/completions"
+
+// Stop tokens.
+var stop []string = []string{
+	"#",
+	"import",
+	"Human",
+	"human",
+	"AI:",
+	"Text:",
+}
+
+// CompletionResult is a single completion result.
+type CompletionResult struct {
+	Text   string      `json:"text"`
+	Output interface{} `json:"output,omitempty"`
+	Index  int         `json:"index"`
+	Status string      `json:"status"`
+	Model  string      `json:"model"`
+}
+
+// CompletionResults is a list of completion results.
+type CompletionResults struct {
+	Id      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Choices []CompletionResult `json:"choices"`
+}
+
+// TypedOutput is a struct type that represents a typed output.
+type TypedOutput struct {
+	Type        string   `json:"type"`
+	Categories  []string `json:"categories"`
+	Pattern     string   `json:"pattern"`
+	Consistency bool     `json:"consistency"`
+	Factuality  bool     `json:"factuality"`
+	Toxicity    bool     `json:"toxicity"`
+}
+
+// CompletionRequest is a struct type that represents a completion request.
+type CompletionRequest struct {
+	Model       string      `json:"model"`
+	Prompt      string      `json:"prompt"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature float64     `json:"temperature"`
+	Output      TypedOutput `json:"output"`
+}
+
+// getCompletions calls the Prediction Guard API to get text completions.
+func getCompletions(request CompletionRequest) (*CompletionResults, error) {
+
+	// Prepare the payload.
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the POST request.
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", url, strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+os.Getenv("PREDICTIONGUARD_TOKEN"))
+	req.Header.Add("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Read the response body into a CompletionResults value.
+	var results CompletionResults
+	if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+func main() {
+
+	// Get the text to classify from a command line argument.
+	if len(os.Args) < 2 {
+		log.Fatal("Please provide some text to classify as an argument.")
+	}
+	input := os.Args[1]
+
+	// Load the pool of labeled examples.
+	f, err := os.Open("examples.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	var examples []fewshot.Example
+	if err := json.NewDecoder(f).Decode(&examples); err != nil {
+		log.Fatal(err)
+	}
+
+	// Connect to Cohere.
+	co, err := embedding.NewCohere(os.Getenv("COHERE_API_KEY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Embed the pool of examples and configure how the prompt is built.
+	// Rather than always showing the same demonstrations, we show the four
+	// examples that are most similar to the input, within a token budget.
+	ctx := context.Background()
+	builder, err := fewshot.NewBuilder(ctx, co, examples)
+	if err != nil {
+		log.Fatal(err)
+	}
+	builder.Instruction = "Classify the sentiment of the text. Use the label NEU for neutral sentiment, NEG for negative sentiment, and POS for positive sentiment."
+	builder.InputPrefix = "Text: "
+	builder.OutputPrefix = "Sentiment: "
+	builder.K = 4
+	builder.MaxTokens = 200
+
+	prompt, err := builder.Prompt(ctx, input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Prompt the LLM with the assembled few-shot prompt.
+	request := CompletionRequest{
+		Prompt: prompt,
+		Model:  "Nous-Hermes-Llama2-13B",
+	}
+	response, err := getCompletions(request)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Post process the completion, truncating on any stop tokens.
+	completion := string(response.Choices[0].Text)
+	for _, s := range stop {
+		if strings.Contains(completion, s) {
+			completion = completion[:strings.Index(completion, s)]
+		}
+	}
+	completion = strings.TrimSpace(completion)
+
+	// Print the prompt and the completion.
+	fmt.Println("\n" + prompt + completion)
+}
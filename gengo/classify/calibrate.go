@@ -0,0 +1,108 @@
+package classify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Example is an input with its correct label, used to calibrate a
+// classifier.
+type Example struct {
+	Text  string `json:"text"`
+	Label string `json:"label"`
+}
+
+// Calibration reports how well a classifier fit a set of examples.
+type Calibration struct {
+	Temperature float64 `json:"temperature"`
+	Accuracy    float64 `json:"accuracy"`
+
+	// LogLoss is the mean negative log probability of the correct labels
+	// at the chosen temperature. Lower is better.
+	LogLoss float64 `json:"log_loss"`
+}
+
+// Calibrate sets the classifier's temperature so that its confidences
+// match how often it is right on the examples, by minimizing the log loss
+// of the correct labels. Scoring is done once per example, so it costs the
+// same as classifying them.
+func (c *Classifier) Calibrate(ctx context.Context, examples []Example) (*Calibration, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("classify: no examples to calibrate with")
+	}
+
+	// Score every example and note which label is correct.
+	index := map[string]int{}
+	for i, l := range c.Labels {
+		index[l.Name] = i
+	}
+	scores := make([][]float64, len(examples))
+	correct := make([]int, len(examples))
+	hits := 0
+	for i, ex := range examples {
+		want, ok := index[ex.Label]
+		if !ok {
+			return nil, fmt.Errorf("classify: example %d has unknown label %q", i, ex.Label)
+		}
+		s, err := c.score(ctx, ex.Text)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = s
+		correct[i] = want
+		if argmax(s) == want {
+			hits++
+		}
+	}
+
+	// Search temperatures on a log scale from 0.01 to 100, then refine
+	// around the best.
+	best, bestLoss := 1.0, logLoss(scores, correct, 1)
+	for e := -2.0; e <= 2.0; e += 0.1 {
+		t := math.Pow(10, e)
+		if loss := logLoss(scores, correct, t); loss < bestLoss {
+			best, bestLoss = t, loss
+		}
+	}
+	for step := best / 10; step > best/1000; step /= 10 {
+		for t := best - 9*step; t <= best+9*step; t += step {
+			if t <= 0 {
+				continue
+			}
+			if loss := logLoss(scores, correct, t); loss < bestLoss {
+				best, bestLoss = t, loss
+			}
+		}
+	}
+
+	c.Temperature = best
+	calibration := Calibration{
+		Temperature: best,
+		Accuracy:    float64(hits) / float64(len(examples)),
+		LogLoss:     bestLoss,
+	}
+	return &calibration, nil
+}
+
+// logLoss is the mean negative log probability of the correct labels.
+func logLoss(scores [][]float64, correct []int, temperature float64) float64 {
+	sum := 0.0
+	for i, s := range scores {
+		p := softmax(s, temperature)[correct[i]]
+		sum -= math.Log(math.Max(p, 1e-12))
+	}
+	return sum / float64(len(scores))
+}
+
+// argmax returns the index of the highest score.
+func argmax(scores []float64) int {
+	best := 0
+	for i, s := range scores {
+		if s > scores[best] {
+			best = i
+		}
+	}
+	return best
+}
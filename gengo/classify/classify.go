@@ -0,0 +1,144 @@
+// Package classify picks a label for a piece of text by scoring every
+// candidate label, rather than asking a model to generate one, and returns
+// the confidence in each.
+package classify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Label is a candidate class. The Description helps scorers that compare
+// meaning, so "purchase" might be described as "the user wants to buy a
+// product".
+type Label struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Labels builds labels from bare names.
+func Labels(names ...string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		labels[i] = Label{Name: name}
+	}
+	return labels
+}
+
+// Scorer scores how well each label fits the input. Scores are treated as
+// logits: higher is better and only the differences between them matter.
+type Scorer interface {
+	Score(ctx context.Context, input string, labels []Label) ([]float64, error)
+}
+
+// Probability is the confidence in a single label.
+type Probability struct {
+	Label       string  `json:"label"`
+	Probability float64 `json:"probability"`
+}
+
+// Unknown is the label of a result the classifier abstained on.
+const Unknown = "unknown"
+
+// Result is the outcome of classifying an input.
+type Result struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+
+	// Abstained is set when the most likely label was below the
+	// classifier's threshold. Label is then Unknown.
+	Abstained bool `json:"abstained,omitempty"`
+
+	// Distribution holds the probability of every label, most likely
+	// first.
+	Distribution []Probability `json:"distribution"`
+}
+
+// Classifier assigns one of its labels to an input.
+type Classifier struct {
+	Labels []Label
+	Scorer Scorer
+
+	// Temperature divides the scores before they are turned into
+	// probabilities, so confidences can be calibrated to how often the
+	// classifier is right. Zero means 1. See Calibrate.
+	Temperature float64
+
+	// Threshold is the confidence below which the classifier abstains
+	// and returns Unknown rather than guess.
+	Threshold float64
+}
+
+// New returns a classifier that scores labels with the scorer.
+func New(scorer Scorer, labels ...Label) *Classifier {
+	return &Classifier{
+		Labels: labels,
+		Scorer: scorer,
+	}
+}
+
+// Classify scores every label and returns the most likely one along with
+// the probability of each. If the most likely label's probability is
+// below the threshold the result is Unknown.
+func (c *Classifier) Classify(ctx context.Context, input string) (*Result, error) {
+	scores, err := c.score(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	probs := softmax(scores, c.Temperature)
+	result := Result{Distribution: make([]Probability, len(c.Labels))}
+	for i, l := range c.Labels {
+		result.Distribution[i] = Probability{Label: l.Name, Probability: probs[i]}
+	}
+	sort.SliceStable(result.Distribution, func(i, j int) bool {
+		return result.Distribution[i].Probability > result.Distribution[j].Probability
+	})
+	result.Label = result.Distribution[0].Label
+	result.Confidence = result.Distribution[0].Probability
+	if result.Confidence < c.Threshold {
+		result.Label = Unknown
+		result.Abstained = true
+	}
+	return &result, nil
+}
+
+// score gets the score of every label for the input.
+func (c *Classifier) score(ctx context.Context, input string) ([]float64, error) {
+	if len(c.Labels) == 0 {
+		return nil, errors.New("classify: no labels")
+	}
+	scores, err := c.Scorer.Score(ctx, input, c.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(c.Labels) {
+		return nil, fmt.Errorf("classify: got %d scores for %d labels", len(scores), len(c.Labels))
+	}
+	return scores, nil
+}
+
+// softmax turns scores into probabilities that sum to one, dividing them
+// by the temperature first.
+func softmax(scores []float64, temperature float64) []float64 {
+	if temperature <= 0 {
+		temperature = 1
+	}
+	highest := math.Inf(-1)
+	for _, s := range scores {
+		highest = math.Max(highest, s)
+	}
+	probs := make([]float64, len(scores))
+	sum := 0.0
+	for i, s := range scores {
+		probs[i] = math.Exp((s - highest) / temperature)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
@@ -0,0 +1,107 @@
+package classify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// DefaultSharpness scales cosine similarities into logits. Embeddings of
+// related texts have similarities within a few hundredths of each other,
+// so without scaling every label would look equally likely.
+const DefaultSharpness = 20
+
+// EmbeddingScorer scores each label by the cosine similarity of its
+// embedding to the input's. It needs no completion model, so it works as
+// a fallback for backends without log probabilities.
+type EmbeddingScorer struct {
+	Embedder embedding.Embedder
+
+	// Sharpness multiplies the similarities. If zero, DefaultSharpness is
+	// used.
+	Sharpness float64
+
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+// NewEmbeddingScorer returns a scorer that compares embeddings.
+func NewEmbeddingScorer(e embedding.Embedder) *EmbeddingScorer {
+	return &EmbeddingScorer{Embedder: e}
+}
+
+// Score implements Scorer.
+func (s *EmbeddingScorer) Score(ctx context.Context, input string, labels []Label) ([]float64, error) {
+	labelVectors, err := s.labelVectors(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := embedding.EmbedOne(ctx, s.Embedder, input)
+	if err != nil {
+		return nil, err
+	}
+
+	sharpness := s.Sharpness
+	if sharpness == 0 {
+		sharpness = DefaultSharpness
+	}
+	scores := make([]float64, len(labels))
+	for i, v := range labelVectors {
+		similarity, err := embedding.CosineSimilarity(vector, v)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = similarity * sharpness
+	}
+	return scores, nil
+}
+
+// labelVectors embeds the labels, remembering them for later calls.
+func (s *EmbeddingScorer) labelVectors(ctx context.Context, labels []Label) ([][]float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vectors == nil {
+		s.vectors = map[string][]float64{}
+	}
+
+	missing := []string{}
+	for _, l := range labels {
+		if _, ok := s.vectors[labelText(l)]; !ok {
+			missing = append(missing, labelText(l))
+		}
+	}
+	if len(missing) > 0 {
+		vectors, err := embedding.EmbedAll(ctx, s.Embedder, missing, embedding.DefaultBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for i, text := range missing {
+			s.vectors[text] = vectors[i]
+		}
+	}
+
+	out := make([][]float64, len(labels))
+	for i, l := range labels {
+		out[i] = s.vectors[labelText(l)]
+	}
+	return out, nil
+}
+
+// labelText is the text embedded for a label.
+func labelText(l Label) string {
+	if l.Description == "" {
+		return l.Name
+	}
+	return l.Name + ": " + l.Description
+}
+
+// NewScorer returns a LogprobScorer if the completer supports log
+// probabilities and an EmbeddingScorer otherwise.
+func NewScorer(c llm.Completer, e embedding.Embedder) Scorer {
+	if lp, ok := c.(Logprober); ok {
+		return LogprobScorer{Model: lp}
+	}
+	return NewEmbeddingScorer(e)
+}
@@ -0,0 +1,74 @@
+package classify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Logprober is implemented by completion backends that can report the log
+// probability of each token of a continuation of a prompt. It is optional:
+// NewScorer falls back to an EmbeddingScorer for backends without it.
+type Logprober interface {
+	Logprobs(ctx context.Context, prompt string, continuation string) ([]float64, error)
+}
+
+// LogprobScorer scores each label by the mean log probability of the
+// tokens of the label's name after a prompt. Taking the mean rather than
+// the sum keeps labels of many tokens from scoring lower just for being
+// long.
+type LogprobScorer struct {
+	Model Logprober
+
+	// Prompt builds the prompt the label name is appended to. If nil,
+	// DefaultPrompt is used.
+	Prompt func(input string, labels []Label) string
+}
+
+// DefaultPrompt asks for a label for the input in the workshop's
+// instruction format.
+func DefaultPrompt(input string, labels []Label) string {
+	lines := []string{}
+	for _, l := range labels {
+		if l.Description != "" {
+			lines = append(lines, "- "+l.Name+": "+l.Description)
+			continue
+		}
+		lines = append(lines, "- "+l.Name)
+	}
+	return fmt.Sprintf(`### Instruction:
+Respond with the label that best fits the text in the below input. The labels are:
+%s
+
+### Input:
+%s
+
+### Response:
+`, strings.Join(lines, "\n"), input)
+}
+
+// Score implements Scorer.
+func (s LogprobScorer) Score(ctx context.Context, input string, labels []Label) ([]float64, error) {
+	prompt := DefaultPrompt
+	if s.Prompt != nil {
+		prompt = s.Prompt
+	}
+	p := prompt(input, labels)
+
+	scores := make([]float64, len(labels))
+	for i, l := range labels {
+		lps, err := s.Model.Logprobs(ctx, p, l.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(lps) == 0 {
+			return nil, fmt.Errorf("classify: no log probabilities for label %q", l.Name)
+		}
+		sum := 0.0
+		for _, lp := range lps {
+			sum += lp
+		}
+		scores[i] = sum / float64(len(lps))
+	}
+	return scores, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/rag"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// runAsk answers a question from the most relevant chunk in the index.
+func runAsk(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("ask", "question", &opts)
+	opts.indexFlag(fs)
+	fs.StringVar(&opts.model, "model", "", "model to answer with")
+	grounding := fs.String("grounding", "off", "check the answer against the chunk: off, flag, or replace an unsupported answer with the fallback")
+	judge := fs.Bool("judge", false, "with -grounding, also ask the model whether the chunk supports the answer")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	question := strings.Join(fs.Args(), " ")
+	if question == "" {
+		return usagef("no question given")
+	}
+	if *grounding != "off" && *grounding != "flag" && *grounding != "replace" {
+		return usagef("unknown -grounding %q", *grounding)
+	}
+
+	// Load the index.
+	chunks, err := vectorstore.Load(opts.index)
+	if err != nil {
+		return err
+	}
+
+	// Get the retrieval based answer.
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	assistant := rag.NewAssistant(newCompleter(), embedder, chunks)
+	if opts.model != "" {
+		assistant.Model = opts.model
+	}
+	if *grounding != "off" {
+		assistant.Grounder = rag.NewGrounder(embedder)
+		assistant.ReplaceUngrounded = *grounding == "replace"
+		if *judge {
+			assistant.Grounder.Judge = assistant.Completer
+			assistant.Grounder.JudgeModel = assistant.Model
+		}
+	}
+	answer, err := assistant.Answer(ctx, question)
+	if err != nil {
+		return err
+	}
+
+	return opts.print(answer, func(w io.Writer) {
+		fmt.Fprintln(w, answer.Text)
+		if !rag.IsFallback(answer.Text) {
+			fmt.Fprintf(w, "\nSource: %s\n", answer.Chunk.Citation())
+		}
+		if g := answer.Grounding; g != nil && !g.Grounded {
+			fmt.Fprintf(w, "\n(warning: the answer may not be supported by the source, %.0f%% of its sentences match it)\n", g.Support*100)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/classify"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/rag"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// exchange is a single turn of the chat command in JSON mode.
+type exchange struct {
+	Input    string `json:"input"`
+	Response string `json:"response"`
+}
+
+// runChat chats with the user over stdin. With -context every question is
+// answered from a context file; with -index informational questions are
+// answered from the index and everything else is chit-chat.
+func runChat(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("chat", "", &opts)
+	opts.modelFlag(fs)
+	fs.StringVar(&opts.index, "index", "", "file of vectorized chunks to answer questions from")
+	contextFile := fs.String("context", "", "file of context to answer questions from")
+	router := fs.String("router", "model", "how -index decides what is a question: model prompts the router model, embedding compares with label descriptions")
+	threshold := fs.Float64("threshold", 0, "with -router embedding, the confidence below which the input is treated as unclear")
+	unsure := fs.String("unsure", "clarify", "what to do with unclear input: clarify asks a question back, chat or answer route it there")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *router != "model" && *router != "embedding" {
+		return usagef("unknown -router %q", *router)
+	}
+	if *router == "embedding" && opts.index == "" {
+		return usagef("-router embedding needs -index")
+	}
+	if *unsure != "clarify" && *unsure != "chat" && *unsure != "answer" {
+		return usagef("unknown -unsure %q", *unsure)
+	}
+	if fs.NArg() != 0 {
+		return usagef("chat takes no arguments")
+	}
+	if *contextFile != "" && opts.index != "" {
+		return usagef("give either -context or -index, not both")
+	}
+
+	// Set up the assistant and whatever it answers from.
+	var (
+		chunks   vectorstore.VectorizedChunks
+		embedder embedding.Embedder
+		passage  string
+	)
+	switch {
+	case *contextFile != "":
+		content, err := os.ReadFile(*contextFile)
+		if err != nil {
+			return err
+		}
+		passage = string(content)
+	case opts.index != "":
+		var err error
+		if chunks, err = vectorstore.Load(opts.index); err != nil {
+			return err
+		}
+		if embedder, err = newEmbedder(); err != nil {
+			return err
+		}
+	}
+	assistant := rag.NewAssistant(newCompleter(), embedder, chunks)
+	if opts.model != "" {
+		assistant.Model = opts.model
+		assistant.ChatModel = opts.model
+	}
+	if *router == "embedding" {
+		assistant.Router = classify.New(classify.NewScorer(assistant.Completer, embedder), rag.RouterLabels...)
+		assistant.Router.Threshold = *threshold
+	}
+	switch *unsure {
+	case "chat":
+		assistant.Unsure = assistant.Chat
+	case "answer":
+		assistant.Unsure = func(ctx context.Context, input string, history rag.ChatContexts) (string, error) {
+			answer, err := assistant.Answer(ctx, input)
+			if err != nil {
+				return "", err
+			}
+			return answer.Text, nil
+		}
+	}
+
+	// Start a cycle of listening for questions and responding to the questions.
+	convo := rag.ChatContexts{}
+	scanner := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		if !opts.json {
+			fmt.Print("\n🧑: ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		input := scanner.Text()
+
+		// Exit if we type "exit".
+		if strings.ToLower(input) == "exit" {
+			break
+		}
+
+		// Handle the input accordingly.
+		var (
+			response string
+			err      error
+		)
+		if passage != "" {
+			response, err = assistant.AnswerFromContext(ctx, passage, input)
+		} else {
+			response, err = assistant.Respond(ctx, input, convo)
+		}
+		if err != nil {
+			return err
+		}
+
+		if opts.json {
+			if err := enc.Encode(exchange{Input: input, Response: response}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Print("\n🤖: " + response + "\n")
+		}
+
+		// Add the chat context to the slice.
+		convo = append(convo, rag.ChatContext{
+			You: input,
+			AI:  response,
+		})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/classify"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/rag"
+)
+
+// presets are the label sets used in the workshop examples.
+var presets = map[string][]classify.Label{
+	"sentiment": {
+		{Name: "POS", Description: "positive sentiment, the writer is happy or pleased"},
+		{Name: "NEU", Description: "neutral sentiment, the writer states facts without feeling"},
+		{Name: "NEG", Description: "negative sentiment, the writer is unhappy or annoyed"},
+	},
+	"intent": {
+		{Name: "purchase", Description: "the user wants to buy a product"},
+		{Name: "chat", Description: "the user wants to talk with someone"},
+		{Name: "return", Description: "the user wants to return a product or get a refund"},
+		{Name: "other", Description: "anything else"},
+	},
+	"router": rag.RouterLabels,
+}
+
+// classification is the output of the classify command for one text.
+type classification struct {
+	Text string `json:"text"`
+	*classify.Result
+}
+
+// runClassify labels each argument, or each line of stdin when there are
+// no arguments, by comparing its embedding to those of the labels.
+func runClassify(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("classify", "[text ...]", &opts)
+	var labels labelList
+	fs.Var(&labels, "label", "candidate label as name or name=description (repeatable)")
+	preset := fs.String("preset", "", "use a workshop label set: "+strings.Join(presetNames(), ", "))
+	threshold := fs.Float64("threshold", 0, "confidence below which the label is "+classify.Unknown)
+	calibrate := fs.String("calibrate", "", "JSON lines file of {\"text\", \"label\"} examples to calibrate the confidences with")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	// Pick the labels.
+	switch {
+	case *preset != "" && len(labels) > 0:
+		return usagef("give either -preset or -label, not both")
+	case *preset != "":
+		p, ok := presets[*preset]
+		if !ok {
+			return usagef("unknown preset %q", *preset)
+		}
+		labels = p
+	case len(labels) < 2:
+		return usagef("give at least two -label flags or a -preset")
+	}
+
+	// Collect the texts to classify.
+	texts := fs.Args()
+	if len(texts) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				texts = append(texts, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(texts) == 0 {
+		return usagef("no text to classify")
+	}
+
+	// Classify them.
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	classifier := classify.New(classify.NewScorer(newCompleter(), embedder), labels...)
+	classifier.Threshold = *threshold
+	if *calibrate != "" {
+		examples, err := loadExamples(*calibrate)
+		if err != nil {
+			return err
+		}
+		calibration, err := classifier.Calibrate(ctx, examples)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "calibrated on %d examples: temperature %.3f, accuracy %.2f, log loss %.3f\n",
+			len(examples), calibration.Temperature, calibration.Accuracy, calibration.LogLoss)
+	}
+	out := make([]classification, len(texts))
+	for i, text := range texts {
+		result, err := classifier.Classify(ctx, text)
+		if err != nil {
+			return err
+		}
+		out[i] = classification{Text: text, Result: result}
+	}
+
+	return opts.print(out, func(w io.Writer) {
+		for _, c := range out {
+			probs := []string{}
+			for _, p := range c.Distribution {
+				probs = append(probs, fmt.Sprintf("%s=%.2f", p.Label, p.Probability))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", c.Label, strings.Join(probs, " "), c.Text)
+		}
+	})
+}
+
+// loadExamples reads labelled examples, one JSON object per line.
+func loadExamples(path string) ([]classify.Example, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	examples := []classify.Example{}
+	dec := json.NewDecoder(f)
+	for {
+		var ex classify.Example
+		err := dec.Decode(&ex)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		examples = append(examples, ex)
+	}
+	return examples, nil
+}
+
+// presetNames returns the names of the label presets in order.
+func presetNames() []string {
+	names := []string{}
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// labelList collects repeated -label flags.
+type labelList []classify.Label
+
+// String implements flag.Value.
+func (l *labelList) String() string {
+	names := []string{}
+	for _, label := range *l {
+		names = append(names, label.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set implements flag.Value.
+func (l *labelList) Set(value string) error {
+	name, description, _ := strings.Cut(value, "=")
+	if name == "" {
+		return fmt.Errorf("expected name or name=description, got %q", value)
+	}
+	*l = append(*l, classify.Label{Name: name, Description: description})
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/consistency"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/guard"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// remoteCompleter wraps the Prediction Guard client so that its failures
+// map to the API and guard exit codes. Completions that do not match their
+// output type and cancelled requests are other failures.
+type remoteCompleter struct {
+	next llm.Completer
+}
+
+// newCompleter connects to Prediction Guard. Prompts and completions pass
+// through the local guard if GENGO_GUARD is "on", requests with the
+// consistency guard are sampled several times, failed requests are retried
+// and, if GENGO_TRACE is set, every request is logged to stderr.
+func newCompleter() llm.Completer {
+	middleware := []llm.Middleware{}
+	if os.Getenv("GENGO_GUARD") == "on" {
+		middleware = append(middleware, guard.Default().Wrap)
+	}
+	middleware = append(middleware,
+		consistency.New(nil).Wrap,
+		llm.Retry(3, time.Second),
+	)
+	if os.Getenv("GENGO_TRACE") != "" {
+		middleware = append(middleware, llm.Logging(traceLogger))
+	}
+	return remoteCompleter{next: llm.Chain(llm.NewClient(""), middleware...)}
+}
+
+// traceLogger logs requests when GENGO_TRACE is set.
+var traceLogger = log.New(os.Stderr, "gengo: ", log.Ltime)
+
+// Complete implements llm.Completer.
+func (r remoteCompleter) Complete(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResults, error) {
+	response, err := r.next.Complete(ctx, request)
+	var outputErr *llm.OutputError
+	switch {
+	case err == nil:
+		return response, nil
+	case local(err), errors.As(err, &outputErr):
+		return nil, err
+	case errors.Is(err, llm.ErrGuard), errors.Is(err, guard.ErrBlocked):
+		return nil, &guardError{err: err}
+	}
+	return nil, &apiError{err: err}
+}
+
+// remoteEmbedder wraps the Cohere client so that its failures map to the
+// API exit code.
+type remoteEmbedder struct {
+	next embedding.Embedder
+}
+
+// newEmbedder connects to Cohere.
+func newEmbedder() (embedding.Embedder, error) {
+	co, err := embedding.NewCohere(os.Getenv("COHERE_API_KEY"))
+	if err != nil {
+		return nil, &apiError{err: err}
+	}
+	middleware := []embedding.Middleware{embedding.Retry(3, time.Second)}
+	if os.Getenv("GENGO_TRACE") != "" {
+		middleware = append(middleware, embedding.Logging(traceLogger))
+	}
+	return remoteEmbedder{next: embedding.Chain(co, middleware...)}, nil
+}
+
+// Embed implements embedding.Embedder.
+func (r remoteEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors, err := r.next.Embed(ctx, texts)
+	switch {
+	case err == nil:
+		return vectors, nil
+	case local(err):
+		return nil, err
+	}
+	return nil, &apiError{err: err}
+}
+
+// local reports whether err comes from this process rather than the API,
+// like an interrupt or an expired deadline.
+func local(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
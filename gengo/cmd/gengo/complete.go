@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/promptfile"
+)
+
+// completion is the output of the complete command.
+type completion struct {
+	Model  string `json:"model"`
+	Text   string `json:"text"`
+	Value  any    `json:"value,omitempty"`
+	Status string `json:"status"`
+}
+
+// runComplete runs a prompt file, or prompt text given with -prompt or
+// on stdin, through an LLM.
+func runComplete(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("complete", "[prompt-file]", &opts)
+	opts.modelFlags(fs)
+	opts.varFlag(fs)
+	prompt := fs.String("prompt", "", "prompt text to use instead of a prompt file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	// Load the prompt and build the request it describes.
+	file, err := loadPrompt(fs, *prompt)
+	if err != nil {
+		return err
+	}
+	opts.override(fs, file)
+	request, err := file.Request(opts.vars)
+	if err != nil {
+		return err
+	}
+
+	// Prompt the LLM.
+	response, err := newCompleter().Complete(ctx, request)
+	if err != nil {
+		return err
+	}
+	choice := response.Choices[0]
+
+	out := completion{
+		Model:  request.Model,
+		Text:   llm.TrimStop(choice.Text, request.Stop),
+		Value:  choice.Value,
+		Status: choice.Status,
+	}
+	return opts.print(out, func(w io.Writer) {
+		if out.Value != nil {
+			printValue(w, out.Value)
+			return
+		}
+		fmt.Fprintln(w, out.Text)
+	})
+}
+
+// printValue writes a typed output value: dates in ISO 8601, lists one
+// item per line and JSON objects as JSON.
+func printValue(w io.Writer, value any) {
+	switch v := value.(type) {
+	case time.Time:
+		if h, m, s := v.Clock(); h == 0 && m == 0 && s == 0 && v.Nanosecond() == 0 {
+			fmt.Fprintln(w, v.Format(time.DateOnly))
+			return
+		}
+		fmt.Fprintln(w, v.Format(time.RFC3339))
+	case []string:
+		for _, item := range v {
+			fmt.Fprintln(w, item)
+		}
+	case map[string]any:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+	default:
+		fmt.Fprintln(w, v)
+	}
+}
+
+// loadPrompt reads the prompt file named by the only argument, the -prompt
+// text, or stdin, in that order of preference.
+func loadPrompt(fs *flag.FlagSet, prompt string) (*promptfile.File, error) {
+	switch {
+	case fs.NArg() > 1:
+		return nil, usagef("expected at most one prompt file")
+	case fs.NArg() == 1:
+		if prompt != "" {
+			return nil, usagef("give either a prompt file or -prompt, not both")
+		}
+		return promptfile.Load(fs.Arg(0))
+	case prompt != "":
+		return promptfile.Parse([]byte(prompt))
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, usagef("no prompt given")
+	}
+	return promptfile.Parse(content)
+}
+
+// override replaces the prompt file's parameters with any that were set
+// explicitly on the command line.
+func (o *options) override(fs *flag.FlagSet, file *promptfile.File) {
+	if isSet(fs, "model") {
+		file.Model = o.model
+	}
+	if isSet(fs, "temperature") {
+		file.Temperature = o.temperature
+	}
+	if isSet(fs, "max-tokens") {
+		file.MaxTokens = o.maxTokens
+	}
+	if isSet(fs, "stop") {
+		file.Stop = o.stop
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// embedded is a text and its embedding, as printed by the embed command.
+type embedded struct {
+	Text   string    `json:"chunk"`
+	Vector []float64 `json:"vector"`
+}
+
+// runEmbed prints the embedding of each argument, or of each line of stdin
+// when there are no arguments.
+func runEmbed(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("embed", "[text ...]", &opts)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	// Collect the texts to embed.
+	texts := fs.Args()
+	if len(texts) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				texts = append(texts, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if len(texts) == 0 {
+		return usagef("no text to embed")
+	}
+
+	// Embed them in concurrent batches. The embedder already retries
+	// failed requests.
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	pipeline := embedding.NewPipeline(embedder)
+	pipeline.Attempts = 1
+	vectors, err := pipeline.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	out := make([]embedded, len(texts))
+	for i, text := range texts {
+		out[i] = embedded{Text: text, Vector: vectors[i]}
+	}
+	return opts.print(out, func(w io.Writer) {
+		for _, v := range out {
+			fields := make([]string, len(v.Vector))
+			for i, f := range v.Vector {
+				fields[i] = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			fmt.Fprintln(w, strings.Join(fields, " "))
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// options holds the flags shared by the subcommands. Each subcommand only
+// registers the ones it uses.
+type options struct {
+	json        bool
+	model       string
+	temperature float64
+	maxTokens   int
+	stop        stopList
+	index       string
+	vars        vars
+}
+
+// newFlagSet returns a flag set for a subcommand with a usage message
+// describing its arguments.
+func newFlagSet(name string, args string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gengo %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.json, "json", false, "write output as JSON")
+	return fs
+}
+
+// modelFlag registers the flag naming the model to prompt.
+func (o *options) modelFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.model, "model", "", "model to prompt, overriding the default or the prompt file")
+}
+
+// modelFlags registers the flags that configure a completion request.
+func (o *options) modelFlags(fs *flag.FlagSet) {
+	o.modelFlag(fs)
+	fs.Float64Var(&o.temperature, "temperature", 0, "sampling temperature")
+	fs.IntVar(&o.maxTokens, "max-tokens", 0, "maximum number of tokens to generate (0 uses the API default)")
+	fs.Var(&o.stop, "stop", "stop token to truncate the completion at (repeatable)")
+}
+
+// indexFlag registers the flag naming the file of vectorized chunks.
+func (o *options) indexFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.index, "index", "chunks.json", "file of vectorized chunks")
+}
+
+// varFlag registers the repeatable template variable flag.
+func (o *options) varFlag(fs *flag.FlagSet) {
+	o.vars = vars{}
+	fs.Var(o.vars, "var", "template variable as name=value or name=@file (repeatable)")
+}
+
+// parse parses the command line, converting failures to usage errors.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err: err, printed: true}
+	}
+	return nil
+}
+
+// isSet reports whether the named flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// print writes v as indented JSON when -json is set, and otherwise calls
+// text to write the human readable form.
+func (o *options) print(v any, text func(w io.Writer)) error {
+	if o.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(os.Stdout)
+	return nil
+}
+
+// vars collects repeated -var name=value flags. A value starting with @
+// is read from the named file.
+type vars map[string]string
+
+// String implements flag.Value.
+func (v vars) String() string {
+	pairs := []string{}
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value.
+func (v vars) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	if strings.HasPrefix(value, "@") {
+		content, err := os.ReadFile(value[1:])
+		if err != nil {
+			return err
+		}
+		value = string(content)
+	}
+	v[name] = value
+	return nil
+}
+
+// stopList collects repeated -stop flags.
+type stopList []string
+
+// String implements flag.Value.
+func (s *stopList) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set implements flag.Value.
+func (s *stopList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/crawl"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/extract"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/ingest"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/loader"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+)
+
+// ingestSummary is the output of the ingest command.
+type ingestSummary struct {
+	Sources []string `json:"sources"`
+	ingest.IndexStats
+	Index string `json:"index"`
+}
+
+// runIngest loads websites, files and directories, splits them into chunks,
+// embeds the chunks and writes them to the index file. Only the chunks of
+// documents that changed since the last run are embedded.
+func runIngest(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("ingest", "url|path...", &opts)
+	opts.indexFlag(fs)
+	start := fs.String("start", "", "only keep the markdown of websites after this string")
+	end := fs.String("end", "", "only keep the markdown of websites before this string")
+	rulesFile := fs.String("rules", "", "YAML file of per-site content extraction rules")
+	crawlSites := fs.Bool("crawl", false, "crawl websites, following their links and sitemaps")
+	depth := fs.Int("depth", crawl.DefaultMaxDepth, "with -crawl, the number of links followed from the start page")
+	maxPages := fs.Int("max-pages", crawl.DefaultMaxPages, "with -crawl, the most pages downloaded per site")
+	delay := fs.Duration("delay", crawl.DefaultDelay, "with -crawl, the least time between requests")
+	prefix := fs.String("prefix", "", "with -crawl, only follow links starting with this URL (default the start page's host)")
+	var splitting split.Config
+	fs.StringVar(&splitting.Strategy, "splitter", split.StrategyMarkdown, "how documents are split: markdown, window, sentences or semantic")
+	fs.IntVar(&splitting.Size, "chunk-size", split.DefaultSize, "the most tokens in a chunk")
+	fs.IntVar(&splitting.Overlap, "chunk-overlap", split.DefaultOverlap, "the tokens each chunk repeats from the one before, or -1 for none")
+	fs.Float64Var(&splitting.Breakpoint, "breakpoint", split.DefaultBreakpoint, "with -splitter semantic, the percentile of sentence distances to split above")
+	rebuild := fs.Bool("rebuild", false, "embed every chunk again rather than only those that changed")
+	batchSize := fs.Int("batch-size", embedding.DefaultBatchSize, "the most chunks embedded in one request")
+	concurrency := fs.Int("concurrency", embedding.DefaultConcurrency, "the most embedding requests in flight")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("expected a url, file or directory")
+	}
+
+	// Build the splitter first, so bad splitting flags are reported before
+	// anything is downloaded.
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	if _, err := split.New(splitting, embedder); err != nil {
+		return usagef("%v", err)
+	}
+
+	// Extract the main content of web pages with the site rules, falling
+	// back to the start and end strings.
+	rules := []extract.Rule{}
+	if *rulesFile != "" {
+		var err error
+		if rules, err = extract.LoadRules(*rulesFile); err != nil {
+			return err
+		}
+	}
+	extractor := extract.New(append(rules, extract.Rule{Start: *start, End: *end})...)
+
+	// Load the documents.
+	loaders := []loader.Loader{}
+	for _, target := range fs.Args() {
+		l, err := loader.For(target)
+		if err != nil {
+			return err
+		}
+		if w, ok := l.(*loader.Website); ok {
+			if *crawlSites {
+				c := crawl.New()
+				c.MaxDepth, c.MaxPages, c.Delay, c.Prefix = *depth, *maxPages, *delay, *prefix
+				c.Extractor = extractor
+				c.OnError = func(url string, err error) { fmt.Fprintf(os.Stderr, "gengo ingest: skipping %s: %v\n", url, err) }
+				l = &loader.Site{URL: w.URL, Crawler: c}
+			} else {
+				w.Extractor = extractor
+			}
+		}
+		loaders = append(loaders, l)
+	}
+	docs, err := loader.LoadAll(ctx, loaders...)
+	if err != nil {
+		return err
+	}
+
+	// Split the documents, then embed the new chunks and save them. The
+	// embedder already retries failed requests.
+	pipeline := embedding.NewPipeline(embedder)
+	pipeline.BatchSize, pipeline.Concurrency, pipeline.Attempts = *batchSize, *concurrency, 1
+	if isTerminal(os.Stderr) {
+		pipeline.Progress = func(p embedding.Progress) {
+			fmt.Fprintf(os.Stderr, "\rgengo ingest: embedded %d of %d chunks", p.Embedded, p.Texts)
+			if p.Done+p.Failed == p.Batches {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+	indexer := ingest.Indexer{Path: opts.index, Split: splitting, Embedder: pipeline, Rebuild: *rebuild}
+	stats, err := indexer.Update(ctx, docs)
+	if stats == nil {
+		return err
+	}
+
+	// Report what was saved, even if some chunks could not be embedded.
+	out := ingestSummary{Sources: fs.Args(), IndexStats: *stats, Index: opts.index}
+	if perr := opts.print(out, func(w io.Writer) {
+		fmt.Fprintf(w, "wrote %d chunks from %d documents in %s to %s\n", out.Chunks, out.Documents, strings.Join(out.Sources, ", "), out.Index)
+		fmt.Fprintf(w, "embedded %d chunks; %d documents added, %d changed, %d unchanged, %d removed\n", out.Embedded, out.Added, out.Changed, out.Unchanged, out.Removed)
+		if out.Failed > 0 {
+			fmt.Fprintf(w, "left out %d chunks that could not be embedded; run ingest again to retry them\n", out.Failed)
+		}
+	}); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
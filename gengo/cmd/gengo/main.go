@@ -0,0 +1,159 @@
+// Command gengo runs the workshop's generative AI tasks from the command
+// line: completions from prompt files, chat, ingesting websites and files
+// into an index of embedded chunks, embedding text, similarity search,
+// retrieval augmented answers, parameter sweeps and classification.
+//
+//	gengo <command> [flags] [args]
+//
+// Every command accepts -json to write machine readable output. The exit
+// status is 0 on success, 1 on other failures, 2 on usage errors, 3 when
+// an API call fails and 4 when a guard check (e.g. toxicity) fails.
+//
+// PREDICTIONGUARD_TOKEN and COHERE_API_KEY provide the API credentials.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	exitAPI   = 3
+	exitGuard = 4
+)
+
+// command is a gengo subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands lists the subcommands in the order they are shown in usage.
+var commands = []command{
+	{"complete", "run a prompt file or prompt text through an LLM", runComplete},
+	{"chat", "chat interactively, optionally grounded in a context file or index", runChat},
+	{"ingest", "load websites, files or directories, chunk and embed them into an index", runIngest},
+	{"embed", "print the embedding vectors of some text", runEmbed},
+	{"search", "find the chunks in an index most similar to a query", runSearch},
+	{"ask", "answer a question from the chunks in an index", runAsk},
+	{"sweep", "run a prompt over a range of parameters", runSweep},
+	{"classify", "label text by comparing it with candidate labels", runClassify},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the subcommand named by args[0] and returns the exit code.
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	// Find the subcommand.
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "gengo: unknown command %q\n", args[0])
+		usage()
+		return exitUsage
+	}
+
+	// Run it, cancelling on interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := cmd.run(ctx, args[1:])
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var ue *usageError
+	if !errors.As(err, &ue) || !ue.printed {
+		fmt.Fprintf(os.Stderr, "gengo %s: %v\n", cmd.name, err)
+	}
+	return exitCode(err)
+}
+
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gengo <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'gengo <command> -h' for the flags of a command.")
+}
+
+// exitCode maps an error to the process exit status.
+func exitCode(err error) int {
+	var (
+		ue *usageError
+		ae *apiError
+		ge *guardError
+	)
+	switch {
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.As(err, &ge):
+		return exitGuard
+	case errors.As(err, &ae):
+		return exitAPI
+	}
+	return exitError
+}
+
+// usageError reports a problem with the command line.
+type usageError struct {
+	err     error
+	printed bool
+}
+
+// Error implements the error interface.
+func (e *usageError) Error() string { return e.err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *usageError) Unwrap() error { return e.err }
+
+// usagef returns a usageError with a formatted message.
+func usagef(format string, args ...any) error {
+	return &usageError{err: fmt.Errorf(format, args...)}
+}
+
+// apiError reports a failed call to a remote API.
+type apiError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *apiError) Error() string { return e.err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *apiError) Unwrap() error { return e.err }
+
+// guardError reports a completion rejected by a guard check.
+type guardError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *guardError) Error() string { return e.err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *guardError) Unwrap() error { return e.err }
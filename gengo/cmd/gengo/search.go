@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// runSearch prints the chunks in the index most similar to the query.
+func runSearch(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("search", "query", &opts)
+	opts.indexFlag(fs)
+	k := fs.Int("k", 1, "number of chunks to return")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		return usagef("no query given")
+	}
+
+	// Load the index.
+	chunks, err := vectorstore.Load(opts.index)
+	if err != nil {
+		return err
+	}
+
+	// Embed the query and search for the relevant chunks.
+	embedder, err := newEmbedder()
+	if err != nil {
+		return err
+	}
+	vector, err := embedding.EmbedOne(ctx, embedder, query)
+	if err != nil {
+		return err
+	}
+	results, err := chunks.Search(vector, *k)
+	if err != nil {
+		return err
+	}
+
+	return opts.print(results, func(w io.Writer) {
+		for i, r := range results {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "[%.4f] %s\n%s\n", r.Similarity, r.Citation(), r.Content)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/sweep"
+)
+
+// axes collects repeated -grid field=values flags.
+type axes sweep.Grid
+
+// String implements flag.Value.
+func (a *axes) String() string {
+	fields := []string{}
+	for _, axis := range *a {
+		fields = append(fields, axis.Field+"="+strings.Join(axis.Values, ","))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Set implements flag.Value.
+func (a *axes) Set(s string) error {
+	axis, err := sweep.ParseAxis(s)
+	if err != nil {
+		return err
+	}
+	*a = append(*a, axis)
+	return nil
+}
+
+// runSweep runs a prompt over every combination of a grid of completion
+// request fields and reports on the samples.
+func runSweep(ctx context.Context, args []string) error {
+	var opts options
+	fs := newFlagSet("sweep", "[prompt-file]", &opts)
+	opts.modelFlags(fs)
+	opts.varFlag(fs)
+	var grid axes
+	fs.Var(&grid, "grid", "request field and values to sweep, as field=start:end:step or field=a,b,c (repeatable; default temperature=0.1:2.0:0.4)")
+	prompt := fs.String("prompt", "", "prompt text to use instead of a prompt file")
+	samples := fs.Int("samples", 3, "completions to generate per combination")
+	concurrency := fs.Int("concurrency", 2, "requests in flight at once")
+	rate := fs.Float64("rate", 1, "maximum requests per second (0 for no limit)")
+	format := fs.String("format", "table", "report format: table, csv or json")
+	out := fs.String("out", "", "file to write the report to instead of stdout")
+	measureSimilarity := fs.Bool("similarity", false, "measure the embedding similarity of the samples (requires COHERE_API_KEY)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if opts.json {
+		*format = "json"
+	}
+	write, err := reportWriter(*format)
+	if err != nil {
+		return err
+	}
+	if len(grid) == 0 {
+		if err := grid.Set("temperature=0.1:2.0:0.4"); err != nil {
+			return err
+		}
+	}
+
+	// Load the prompt and build the base request it describes.
+	file, err := loadPrompt(fs, *prompt)
+	if err != nil {
+		return err
+	}
+	opts.override(fs, file)
+	request, err := file.Request(opts.vars)
+	if err != nil {
+		return err
+	}
+
+	// Run the sweep.
+	runner := sweep.Runner{
+		Completer:   newCompleter(),
+		Samples:     *samples,
+		Concurrency: *concurrency,
+	}
+	if *rate > 0 {
+		runner.Interval = time.Duration(float64(time.Second) / *rate)
+	}
+	if *measureSimilarity {
+		if runner.Embedder, err = newEmbedder(); err != nil {
+			return err
+		}
+	}
+	cells, err := runner.Run(ctx, request, sweep.Grid(grid))
+	if err != nil {
+		return err
+	}
+
+	// Write the report.
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, cells); err != nil {
+		return err
+	}
+
+	// Fail if nothing succeeded, so the exit code reflects why.
+	if allFailed(cells) {
+		return sweep.FirstError(cells)
+	}
+	return nil
+}
+
+// reportWriter returns the function writing the named report format.
+func reportWriter(format string) (func(io.Writer, []sweep.Cell) error, error) {
+	switch format {
+	case "table":
+		return sweep.WriteTable, nil
+	case "csv":
+		return sweep.WriteCSV, nil
+	case "json":
+		return sweep.WriteJSON, nil
+	}
+	return nil, &usageError{err: errors.New("-format must be table, csv or json")}
+}
+
+// allFailed reports whether every sample of every cell failed.
+func allFailed(cells []sweep.Cell) bool {
+	for _, c := range cells {
+		if c.Metrics.Errors < c.Metrics.Samples {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
@@ -0,0 +1,327 @@
+// Package consistency implements self-consistency: the same request is
+// sampled several times, the answers are grouped, and the majority answer
+// is returned along with how many of the samples agreed with it. Too little
+// agreement is reported as a failed consistency guard check.
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// Defaults for a Sampler.
+const (
+	DefaultSamples             = 5
+	DefaultTemperature         = 0.7
+	DefaultMinAgreement        = 0.6
+	DefaultSimilarityThreshold = 0.9
+)
+
+// Cluster is a group of samples that give the same answer.
+type Cluster struct {
+	// Answer is the first sample of the cluster, as the model wrote it.
+	Answer  string   `json:"answer"`
+	Samples []string `json:"samples"`
+
+	// Value is the typed output value shared by the samples, if the
+	// request asked for one.
+	Value any `json:"value,omitempty"`
+}
+
+// Result is the majority answer of a set of samples.
+type Result struct {
+	Answer string `json:"answer"`
+	Value  any    `json:"value,omitempty"`
+
+	// Agreement is the fraction of the samples that succeeded that are in
+	// the majority cluster.
+	Agreement float64 `json:"agreement"`
+
+	// Clusters holds every group of samples, largest first.
+	Clusters []Cluster `json:"clusters"`
+
+	// Failed is the number of samples whose request failed, and Errors
+	// their errors. They are left out of the vote.
+	Failed int     `json:"failed,omitempty"`
+	Errors []error `json:"-"`
+
+	// Response is the response of the first sample in the majority
+	// cluster.
+	Response *llm.CompletionResults `json:"-"`
+}
+
+// Sampler draws several completions for a request and finds the majority
+// answer.
+type Sampler struct {
+	Completer llm.Completer
+
+	// Samples is the number of completions drawn.
+	Samples int
+
+	// Temperature is used for requests that have none, since greedy
+	// decoding would return the same answer every time.
+	Temperature float64
+
+	// MinAgreement is the fraction of samples that must agree for the
+	// answer to be consistent.
+	MinAgreement float64
+
+	// Stop tokens the samples are truncated at before being compared,
+	// when the request has none of its own.
+	Stop []string
+
+	// Embedder, if set, groups free text answers whose embeddings have a
+	// cosine similarity of at least SimilarityThreshold, rather than only
+	// those that are the same after normalization.
+	Embedder            embedding.Embedder
+	SimilarityThreshold float64
+}
+
+// New returns a Sampler with the default settings. The completer may be
+// nil if the sampler is only used to Wrap others.
+func New(c llm.Completer) *Sampler {
+	return &Sampler{
+		Completer:           c,
+		Samples:             DefaultSamples,
+		Temperature:         DefaultTemperature,
+		MinAgreement:        DefaultMinAgreement,
+		SimilarityThreshold: DefaultSimilarityThreshold,
+	}
+}
+
+// sample is a completion that succeeded.
+type sample struct {
+	response *llm.CompletionResults
+	text     string
+	value    any
+}
+
+// Sample draws the samples concurrently and returns the majority answer of
+// those that succeed. Failed samples are counted on the result, and only
+// if every sample fails is an error returned. If fewer than MinAgreement
+// of the samples agree, the result is returned along with an
+// *llm.GuardError that matches llm.ErrConsistency.
+func (s *Sampler) Sample(ctx context.Context, request llm.CompletionRequest) (*Result, error) {
+	n := s.Samples
+	if n <= 0 {
+		n = DefaultSamples
+	}
+	if request.Temperature == 0 {
+		request.Temperature = s.Temperature
+	}
+	if request.Stop == nil {
+		request.Stop = s.Stop
+	}
+	request.Output.Consistency = false
+
+	// Draw the samples.
+	responses := make([]*llm.CompletionResults, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = s.Completer.Complete(ctx, request)
+		}(i)
+	}
+	wg.Wait()
+
+	// Keep the ones that succeeded, reading the typed value of any the
+	// completer did not check.
+	result := Result{}
+	samples := []sample{}
+	for i, response := range responses {
+		if errs[i] == nil && len(response.Choices) == 0 {
+			errs[i] = llm.ErrNoChoices
+		}
+		if errs[i] != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, errs[i])
+			continue
+		}
+		choice := response.Choices[0]
+		text := llm.TrimStop(choice.Text, request.Stop)
+		value := choice.Value
+		if value == nil && request.Output.Type != "" {
+			value, _ = llm.ParseOutput(request.Output, text)
+		}
+		samples = append(samples, sample{response: response, text: text, value: value})
+	}
+	if len(samples) == 0 {
+		return nil, result.Errors[0]
+	}
+
+	// Group them.
+	groups, err := s.cluster(ctx, samples, request.Output.Type != "")
+	if err != nil {
+		return nil, err
+	}
+	result.Clusters = make([]Cluster, len(groups))
+	result.Agreement = float64(len(groups[0])) / float64(len(samples))
+	result.Response = samples[groups[0][0]].response
+	for i, g := range groups {
+		c := Cluster{}
+		for _, idx := range g {
+			c.Samples = append(c.Samples, samples[idx].text)
+			if c.Value == nil {
+				c.Value = samples[idx].value
+			}
+		}
+		c.Answer = c.Samples[0]
+		result.Clusters[i] = c
+	}
+	result.Answer = result.Clusters[0].Answer
+	result.Value = result.Clusters[0].Value
+
+	minAgreement := s.MinAgreement
+	if minAgreement == 0 {
+		minAgreement = DefaultMinAgreement
+	}
+	if result.Agreement < minAgreement {
+		message := fmt.Sprintf("%d of %d samples agreed", len(groups[0]), len(samples))
+		if result.Failed > 0 {
+			message += fmt.Sprintf(", %d failed", result.Failed)
+		}
+		return &result, &llm.GuardError{
+			Result: llm.GuardResult{
+				Check:     llm.CheckConsistency,
+				Score:     result.Agreement,
+				Threshold: minAgreement,
+				Message:   message,
+			},
+			Response: result.Response,
+		}
+	}
+	return &result, nil
+}
+
+// cluster groups the samples by answer and returns the indexes of each
+// group, largest first. Every sample is keyed by its normalized typed
+// value, or its normalized text if it has none, so "Yes." and a parsed
+// "yes" fall in the same group. Only free text answers are merged by
+// meaning.
+func (s *Sampler) cluster(ctx context.Context, samples []sample, typed bool) ([][]int, error) {
+	keys := make([]string, len(samples))
+	for i, sm := range samples {
+		if sm.value != nil {
+			keys[i] = Normalize(fmt.Sprint(sm.value))
+			continue
+		}
+		keys[i] = Normalize(sm.text)
+	}
+
+	// Start with the groups of identical answers.
+	groups := [][]int{}
+	index := map[string]int{}
+	for i, k := range keys {
+		g, ok := index[k]
+		if !ok {
+			g = len(groups)
+			index[k] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	// Merge groups of free text answers that mean the same thing.
+	if s.Embedder != nil && !typed && len(groups) > 1 {
+		var err error
+		if groups, err = s.merge(ctx, samples, groups); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i]) > len(groups[j]) })
+	return groups, nil
+}
+
+// merge joins groups whose first answers are similar enough, comparing each
+// group with the largest groups first.
+func (s *Sampler) merge(ctx context.Context, samples []sample, groups [][]int) ([][]int, error) {
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i]) > len(groups[j]) })
+	texts := make([]string, len(groups))
+	for i, g := range groups {
+		texts[i] = samples[g[0]].text
+	}
+	vectors, err := embedding.EmbedAll(ctx, s.Embedder, texts, embedding.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := s.SimilarityThreshold
+	if threshold == 0 {
+		threshold = DefaultSimilarityThreshold
+	}
+	merged := [][]int{}
+	heads := [][]float64{}
+	for i, g := range groups {
+		joined := false
+		for j, head := range heads {
+			similarity, err := embedding.CosineSimilarity(vectors[i], head)
+			if err != nil {
+				return nil, err
+			}
+			if similarity >= threshold {
+				merged[j] = append(merged[j], g...)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			merged = append(merged, g)
+			heads = append(heads, vectors[i])
+		}
+	}
+	for _, g := range merged {
+		sort.Ints(g)
+	}
+	return merged, nil
+}
+
+// Normalize lower cases an answer, drops punctuation and collapses
+// whitespace so that trivially different answers compare equal.
+func Normalize(answer string) string {
+	answer = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, answer)
+	return strings.Join(strings.Fields(answer), " ")
+}
+
+// Wrap returns a completer that samples requests with Output.Consistency
+// set several times from next and returns the majority answer as the only
+// choice. Other requests are passed straight to next.
+func (s *Sampler) Wrap(next llm.Completer) llm.Completer {
+	sampler := *s
+	sampler.Completer = next
+	return completer{sampler: &sampler}
+}
+
+// completer runs requests that ask for consistency through a Sampler.
+type completer struct {
+	sampler *Sampler
+}
+
+// Complete implements llm.Completer.
+func (c completer) Complete(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResults, error) {
+	if !request.Output.Consistency {
+		return c.sampler.Completer.Complete(ctx, request)
+	}
+	result, err := c.sampler.Sample(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response := *result.Response
+	response.Choices = response.Choices[:1]
+	return &response, nil
+}
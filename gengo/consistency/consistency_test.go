@@ -0,0 +1,129 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// errSample is the error of a failed sample.
+var errSample = errors.New("sample failed")
+
+// scripted returns a completer that answers each request with the next
+// answer, failing with errSample for an answer of "!" and returning no
+// choices for an answer of "-".
+func scripted(answers ...string) llm.Completer {
+	next := make(chan string, len(answers))
+	for _, a := range answers {
+		next <- a
+	}
+	return llm.CompleterFunc(func(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResults, error) {
+		switch a := <-next; a {
+		case "!":
+			return nil, errSample
+		case "-":
+			return &llm.CompletionResults{}, nil
+		default:
+			return &llm.CompletionResults{Choices: []llm.CompletionResult{{Text: a}}}, nil
+		}
+	})
+}
+
+// sortedWords returns the normalized words of an answer in sorted order.
+func sortedWords(answer string) string {
+	words := strings.Fields(Normalize(answer))
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// wordEmbedder embeds texts by their words, so answers with the same words
+// in any order are identical.
+var wordEmbedder = embedding.EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		v := make([]float64, 16)
+		for _, w := range strings.Fields(sortedWords(text)) {
+			v[len(w)%16]++
+			v[int(w[0])%16] += 2
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+})
+
+func TestSample(t *testing.T) {
+	categorical := llm.TypedOutput{Type: llm.OutputCategorical, Categories: []string{"yes", "no"}}
+	for _, tc := range []struct {
+		name       string
+		answers    []string
+		output     llm.TypedOutput
+		embedder   embedding.Embedder
+		answer     string
+		agreement  float64
+		failed     int
+		consistent bool
+	}{
+		{"unanimous", []string{"Yes.", "yes", "YES!", " yes ", "Yes"}, llm.TypedOutput{}, nil, "yes", 1, 0, true},
+		{"majority", []string{"yes", "yes", "yes", "no", "maybe"}, llm.TypedOutput{}, nil, "yes", 0.6, 0, true},
+		{"split", []string{"yes", "yes", "no", "no", "maybe"}, llm.TypedOutput{}, nil, "yes", 0.4, 0, false},
+		{"failed samples left out", []string{"yes", "yes", "no", "!", "!"}, llm.TypedOutput{}, nil, "yes", 2.0 / 3, 2, true},
+		{"failed samples lower agreement", []string{"yes", "no", "maybe", "!", "-"}, llm.TypedOutput{}, nil, "", 1.0 / 3, 2, false},
+		{"typed values", []string{"Yes, it is.", "yes", "Yes!", "No", "no way"}, categorical, nil, "yes", 0.6, 0, true},
+		{"merged by meaning", []string{"The capital is Paris", "Paris is the capital.", "the capital is paris", "It is Lyon", "Paris"}, llm.TypedOutput{}, wordEmbedder, "the capital is paris", 0.6, 0, true},
+	} {
+		s := New(scripted(tc.answers...))
+		s.Embedder = tc.embedder
+		result, err := s.Sample(context.Background(), llm.CompletionRequest{Prompt: "Is it?", Output: tc.output})
+		if tc.consistent && err != nil || !tc.consistent && !errors.Is(err, llm.ErrConsistency) {
+			t.Errorf("%s: got error %v", tc.name, err)
+		}
+		if result == nil {
+			t.Fatalf("%s: got no result", tc.name)
+		}
+		if tc.answer != "" && sortedWords(result.Answer) != sortedWords(tc.answer) {
+			t.Errorf("%s: got answer %q, want %q", tc.name, result.Answer, tc.answer)
+		}
+		if result.Agreement != tc.agreement {
+			t.Errorf("%s: got agreement %v, want %v", tc.name, result.Agreement, tc.agreement)
+		}
+		if result.Failed != tc.failed || len(result.Errors) != tc.failed {
+			t.Errorf("%s: got %d failed samples and errors %v, want %d", tc.name, result.Failed, result.Errors, tc.failed)
+		}
+		total := result.Failed
+		for _, c := range result.Clusters {
+			total += len(c.Samples)
+		}
+		if total != len(tc.answers) {
+			t.Errorf("%s: clusters and failures hold %d samples, want %d", tc.name, total, len(tc.answers))
+		}
+		if tc.output.Type != "" && result.Value != tc.answer {
+			t.Errorf("%s: got value %v, want %q", tc.name, result.Value, tc.answer)
+		}
+	}
+}
+
+func TestSampleAllFailed(t *testing.T) {
+	result, err := New(scripted("!", "!", "-", "!", "!")).Sample(context.Background(), llm.CompletionRequest{Prompt: "Is it?"})
+	if result != nil || !errors.Is(err, errSample) && !errors.Is(err, llm.ErrNoChoices) {
+		t.Errorf("got result %+v and error %v, want a sample's error", result, err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	c := New(nil).Wrap(scripted("no", "yes", "yes", "yes", "no", "yes"))
+
+	// Only requests that ask for consistency are sampled.
+	response, err := c.Complete(context.Background(), llm.CompletionRequest{Prompt: "Is it?"})
+	if err != nil || response.Choices[0].Text != "no" {
+		t.Errorf("got %+v and error %v, want the first answer", response, err)
+	}
+	response, err = c.Complete(context.Background(), llm.CompletionRequest{Prompt: "Is it?", Output: llm.TypedOutput{Consistency: true}})
+	if err != nil || len(response.Choices) != 1 || response.Choices[0].Text != "yes" {
+		t.Errorf("got %+v and error %v, want the majority answer", response, err)
+	}
+}
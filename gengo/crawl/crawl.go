@@ -0,0 +1,442 @@
+// Package crawl follows the links of a website from a start page,
+// converting every page it finds to Markdown for indexing. It stays within
+// a URL prefix, reads sitemap.xml, obeys robots.txt, skips pages whose
+// canonical URL it has already seen and waits between requests.
+//
+// The crawler takes its own *http.Client and start URL, so it can be
+// pointed at an httptest.Server:
+//
+//	c := crawl.New()
+//	c.Client = server.Client()
+//	docs, err := c.Crawl(ctx, server.URL+"/docs/")
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/extract"
+)
+
+// Defaults for a Crawler.
+const (
+	DefaultUserAgent   = "gengo-crawler"
+	DefaultMaxDepth    = 2
+	DefaultMaxPages    = 100
+	DefaultConcurrency = 4
+	DefaultDelay       = 500 * time.Millisecond
+)
+
+// maxBody is the most bytes read from a response.
+const maxBody = 10 << 20
+
+// Crawler crawls websites.
+type Crawler struct {
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client    *http.Client
+	UserAgent string
+
+	// Prefix limits the crawl to URLs that start with it. If empty, the
+	// crawl stays on the start page's scheme and host.
+	Prefix string
+
+	// MaxDepth is the number of links followed away from the start page
+	// and the pages in the sitemap. Zero crawls only those pages.
+	MaxDepth int
+
+	// MaxPages is the most pages downloaded. Zero uses DefaultMaxPages.
+	MaxPages int
+
+	// Concurrency is the number of pages downloaded at once. Zero uses
+	// DefaultConcurrency.
+	Concurrency int
+
+	// Delay is the least time between the start of two requests. A longer
+	// Crawl-delay in robots.txt takes precedence.
+	Delay time.Duration
+
+	// Sitemap adds the pages listed in the site's sitemaps to the start
+	// page.
+	Sitemap bool
+
+	// IgnoreRobots crawls pages that robots.txt disallows.
+	IgnoreRobots bool
+
+	// Extractor extracts the main content of each page. If nil, a
+	// readability style extractor with no rules is used. Pages whose
+	// content cannot be extracted are not returned, but their links are
+	// still followed.
+	Extractor *extract.Extractor
+
+	// OnError, if set, is called for every page that could not be
+	// crawled. Such pages are otherwise skipped.
+	OnError func(url string, err error)
+}
+
+// New returns a Crawler with the default settings that reads sitemaps.
+func New() *Crawler {
+	return &Crawler{
+		UserAgent:   DefaultUserAgent,
+		MaxDepth:    DefaultMaxDepth,
+		MaxPages:    DefaultMaxPages,
+		Concurrency: DefaultConcurrency,
+		Delay:       DefaultDelay,
+		Sitemap:     true,
+	}
+}
+
+// report passes an error for a page to OnError.
+func (c *Crawler) report(url string, err error) {
+	if c.OnError != nil {
+		c.OnError(url, err)
+	}
+}
+
+// Crawl crawls the site from the start URL breadth first and returns a
+// Markdown document for each page, in the order they were found. Its
+// source is the page's canonical URL. An error is returned if the start
+// page cannot be crawled; other pages that fail are skipped.
+func (c *Crawler) Crawl(ctx context.Context, start string) ([]document.Document, error) {
+	startURL, err := url.Parse(start)
+	if err != nil {
+		return nil, err
+	}
+	if startURL.Scheme != "http" && startURL.Scheme != "https" {
+		return nil, fmt.Errorf("crawl: %s: not an http or https URL", start)
+	}
+
+	s := &session{
+		crawler: c,
+		client:  c.Client,
+		agent:   c.UserAgent,
+		pacer:   &pacer{delay: c.Delay},
+		seen:    map[string]bool{},
+	}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+	if s.agent == "" {
+		s.agent = DefaultUserAgent
+	}
+	prefix := &url.URL{Scheme: startURL.Scheme, Host: startURL.Host, Path: "/"}
+	if c.Prefix != "" {
+		if prefix, err = url.Parse(c.Prefix); err != nil {
+			return nil, err
+		}
+	}
+	s.prefix = normalize(prefix)
+	maxPages := c.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	// Read robots.txt and the sitemaps.
+	origin := startURL.Scheme + "://" + startURL.Host
+	if !c.IgnoreRobots {
+		s.robots = s.readRobots(ctx, origin+"/robots.txt")
+		s.pacer.delay = max(s.pacer.delay, s.robots.delay)
+	}
+	frontier := []string{}
+	s.enqueue(&frontier, startURL)
+	crawlsStart := len(frontier) == 1
+	if c.Sitemap {
+		sitemaps := []string{origin + "/sitemap.xml"}
+		if s.robots != nil && len(s.robots.sitemaps) > 0 {
+			sitemaps = s.robots.sitemaps
+		}
+		for _, raw := range s.sitemapURLs(ctx, sitemaps) {
+			if u, err := url.Parse(raw); err == nil {
+				s.enqueue(&frontier, u)
+			}
+		}
+	}
+	if len(frontier) == 0 {
+		return nil, fmt.Errorf("crawl: %s is outside %s or disallowed by robots.txt", start, s.prefix)
+	}
+
+	// Crawl a level at a time so the order of the pages does not depend
+	// on which downloads finish first.
+	docs := []document.Document{}
+	canonicals := map[string]bool{}
+	fetched := 0
+	for depth := 0; len(frontier) > 0 && fetched < maxPages; depth++ {
+		frontier = frontier[:min(len(frontier), maxPages-fetched)]
+		fetched += len(frontier)
+		pages, errs := s.fetchAll(ctx, frontier)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if depth == 0 && crawlsStart && errs[0] != nil {
+			return nil, errs[0]
+		}
+
+		next := []string{}
+		for i, p := range pages {
+			if errs[i] != nil {
+				c.report(frontier[i], errs[i])
+				continue
+			}
+			if p.err != nil {
+				c.report(frontier[i], p.err)
+			}
+			if !canonicals[p.canonical] && p.index && p.err == nil {
+				canonicals[p.canonical] = true
+				docs = append(docs, p.document(depth))
+			}
+			if depth < c.MaxDepth && p.follow {
+				for _, link := range p.links {
+					s.enqueue(&next, link)
+				}
+			}
+		}
+		frontier = next
+	}
+	return docs, nil
+}
+
+// session is the state of one crawl.
+type session struct {
+	crawler *Crawler
+	client  *http.Client
+	agent   string
+	prefix  string
+	robots  *robots
+	pacer   *pacer
+
+	// seen holds the normalized URLs that have been queued, and the
+	// canonical URLs of the pages downloaded.
+	seen map[string]bool
+}
+
+// enqueue adds a URL to the queue if it is in scope, allowed and has not
+// been seen.
+func (s *session) enqueue(queue *[]string, u *url.URL) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	key := normalize(u)
+	if s.seen[key] || !strings.HasPrefix(key, s.prefix) || !s.robots.allowed(u.RequestURI()) {
+		return
+	}
+	s.seen[key] = true
+	*queue = append(*queue, key)
+}
+
+// fetchAll downloads the pages with up to Concurrency requests at once.
+func (s *session) fetchAll(ctx context.Context, urls []string) ([]*page, []error) {
+	concurrency := s.crawler.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	pages := make([]*page, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, u string) {
+			defer func() { <-sem; wg.Done() }()
+			pages[i], errs[i] = s.fetch(ctx, u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	// Pages that were redirected or declare a canonical URL should not be
+	// downloaded again under that URL.
+	for _, p := range pages {
+		if p != nil {
+			s.seen[p.canonical] = true
+			s.seen[p.final] = true
+		}
+	}
+	return pages, errs
+}
+
+// get sends a GET request once the pacer allows it.
+func (s *session) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	if err := s.pacer.wait(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", s.agent)
+	return s.client.Do(req)
+}
+
+// readRobots downloads and parses robots.txt. A missing or unreadable file
+// allows everything.
+func (s *session) readRobots(ctx context.Context, rawURL string) *robots {
+	res, err := s.get(ctx, rawURL)
+	if err != nil {
+		s.crawler.report(rawURL, err)
+		return &robots{}
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return &robots{}
+	}
+	return parseRobots(io.LimitReader(res.Body, maxBody), s.agent)
+}
+
+// page is a downloaded web page.
+type page struct {
+	url       string
+	final     string
+	canonical string
+	title     string
+	markdown  string
+	links     []*url.URL
+	fetched   time.Time
+
+	// index and follow are false if the page's robots meta tag says
+	// noindex or nofollow.
+	index  bool
+	follow bool
+
+	// err is why the content could not be extracted.
+	err error
+}
+
+// fetch downloads a page and converts it to Markdown.
+func (s *session) fetch(ctx context.Context, rawURL string) (*page, error) {
+	res, err := s.get(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(rawURL, res)
+	}
+	if t := res.Header.Get("Content-Type"); t != "" && !strings.Contains(t, "html") {
+		return nil, fmt.Errorf("crawl: %s: not an HTML page (%s)", rawURL, t)
+	}
+	html, err := goquery.NewDocumentFromReader(io.LimitReader(res.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("crawl: %s: %w", rawURL, err)
+	}
+
+	// Links are relative to the final URL after redirects, or the page's
+	// <base>.
+	extract.ResolveLinks(html, res.Request.URL)
+	p := &page{
+		url:     rawURL,
+		final:   normalize(res.Request.URL),
+		fetched: time.Now().UTC(),
+		index:   true,
+		follow:  true,
+	}
+	p.canonical = p.final
+	if href, ok := html.Find(`link[rel="canonical"]`).Attr("href"); ok {
+		if u, err := res.Request.URL.Parse(strings.TrimSpace(href)); err == nil {
+			p.canonical = normalize(u)
+		}
+	}
+	if content, ok := html.Find(`meta[name="robots"]`).Attr("content"); ok {
+		content = strings.ToLower(content)
+		p.index = !strings.Contains(content, "noindex") && !strings.Contains(content, "none")
+		p.follow = !strings.Contains(content, "nofollow") && !strings.Contains(content, "none")
+	}
+	html.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		rel, _ := a.Attr("rel")
+		if u, err := url.Parse(href); err == nil && !strings.Contains(rel, "nofollow") {
+			p.links = append(p.links, u)
+		}
+	})
+
+	// Extract the content last, since it removes the rest of the page.
+	content, err := s.crawler.Extractor.Extract(p.final, html)
+	if err != nil {
+		p.err = err
+		return p, nil
+	}
+	p.title, p.markdown = content.Title, content.Markdown
+	return p, nil
+}
+
+// document returns the page as a document.
+func (p *page) document(depth int) document.Document {
+	title := p.title
+	if title == "" {
+		title = p.canonical
+	}
+	return document.Document{
+		Source:  p.canonical,
+		Title:   title,
+		Format:  document.FormatMarkdown,
+		Content: p.markdown,
+		Metadata: map[string]string{
+			"url":     p.url,
+			"depth":   strconv.Itoa(depth),
+			"fetched": p.fetched.Format(time.RFC3339),
+		},
+	}
+}
+
+// statusError is the error for a response that is not 200 OK.
+func statusError(rawURL string, res *http.Response) error {
+	return fmt.Errorf("crawl: %s: %s", rawURL, res.Status)
+}
+
+// normalize returns the form of a URL used to tell pages apart: without
+// the fragment or a default port, with a lower case scheme and host, and
+// with at least a / for a path.
+func normalize(u *url.URL) string {
+	n := *u
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.User = nil
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if host, port, ok := strings.Cut(n.Host, ":"); ok && (n.Scheme == "http" && port == "80" || n.Scheme == "https" && port == "443") {
+		n.Host = host
+	}
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return n.String()
+}
+
+// pacer spaces out the start of requests.
+type pacer struct {
+	mu    sync.Mutex
+	delay time.Duration
+	next  time.Time
+}
+
+// wait blocks until the next request may start.
+func (p *pacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.delay)
+	p.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
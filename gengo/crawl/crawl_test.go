@@ -0,0 +1,190 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// site is a small website that records the paths requested from it.
+type site struct {
+	*httptest.Server
+	mu        sync.Mutex
+	requested []string
+}
+
+// pages maps each path of the test site to the paths it links to.
+var pages = map[string][]string{
+	"/":            {"/a", "/b", "/private/secret", "/dup", "https://example.com/elsewhere"},
+	"/a":           {"/a/deep", "/#top"},
+	"/a/deep":      {"/a/deeper"},
+	"/a/deeper":    {},
+	"/b":           {"/a", "/nofollow"},
+	"/dup":         {},
+	"/nofollow":    {"/b/hidden"},
+	"/b/hidden":    {},
+	"/from-map":    {},
+	"/private/map": {},
+}
+
+// newSite starts the test site. /dup declares /a as its canonical URL,
+// /private is disallowed by robots.txt, and /from-map is only in the
+// sitemap.
+func newSite(t *testing.T) *site {
+	s := &site{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requested = append(s.requested, r.URL.Path)
+		s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private\n\nSitemap: %s/sitemap.xml\n", s.URL)
+			return
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprintf(w, `<?xml version="1.0"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+				`<url><loc>%[1]s/from-map</loc></url><url><loc>%[1]s/private/map</loc></url></urlset>`, s.URL)
+			return
+		}
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		var b strings.Builder
+		b.WriteString("<html><head><title>Page " + r.URL.Path + "</title>")
+		if r.URL.Path == "/dup" {
+			b.WriteString(`<link rel="canonical" href="/a">`)
+		}
+		b.WriteString("</head><body><nav><a href=\"/\">Home</a></nav><article><h1>Page " + r.URL.Path + "</h1>")
+		for i := 0; i < 3; i++ {
+			b.WriteString("<p>This paragraph is long enough to count as the main content of the page, so the extractor keeps it.</p>")
+		}
+		for _, link := range links {
+			rel := ""
+			if link == "/nofollow" || r.URL.Path == "/nofollow" {
+				rel = ` rel="nofollow"`
+			}
+			fmt.Fprintf(&b, `<p><a href="%s"%s>%s</a></p>`, link, rel, link)
+		}
+		b.WriteString("</article></body></html>")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, b.String())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// paths returns the paths requested from the site, sorted.
+func (s *site) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := append([]string(nil), s.requested...)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCrawl(t *testing.T) {
+	s := newSite(t)
+	c := New()
+	c.Client = s.Client()
+	c.Delay = 0
+	c.MaxDepth = 2
+	var errs []string
+	c.OnError = func(url string, err error) { errs = append(errs, url+": "+err.Error()) }
+
+	docs, err := c.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Errorf("unexpected errors: %q", errs)
+	}
+
+	// The start page and the sitemap come first, then each level of links
+	// in the order they were found. /dup is the same page as /a, and
+	// /nofollow is only linked with rel="nofollow".
+	sources := []string{}
+	for _, d := range docs {
+		sources = append(sources, strings.TrimPrefix(d.Source, s.URL))
+	}
+	want := []string{"/", "/from-map", "/a", "/b", "/a/deep"}
+	if strings.Join(sources, " ") != strings.Join(want, " ") {
+		t.Errorf("got pages %q, want %q", sources, want)
+	}
+	for _, d := range docs {
+		if d.Metadata["depth"] == "" || d.Title == "" || !strings.Contains(d.Content, "main content") {
+			t.Errorf("page %s has title %q, metadata %v and content %q", d.Source, d.Title, d.Metadata, d.Content)
+		}
+	}
+
+	// Disallowed pages and pages beyond the depth limit are never
+	// requested, and no page is requested twice.
+	requested := s.paths()
+	wantRequested := []string{"/", "/a", "/a/deep", "/b", "/dup", "/from-map", "/robots.txt", "/sitemap.xml"}
+	if strings.Join(requested, " ") != strings.Join(wantRequested, " ") {
+		t.Errorf("requested %q, want %q", requested, wantRequested)
+	}
+}
+
+func TestCrawlMaxPages(t *testing.T) {
+	s := newSite(t)
+	c := New()
+	c.Client = s.Client()
+	c.Delay = 0
+	c.MaxPages = 3
+
+	docs, err := c.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 {
+		t.Errorf("got %d pages, want 3", len(docs))
+	}
+}
+
+func TestCrawlIgnoreRobots(t *testing.T) {
+	s := newSite(t)
+	c := New()
+	c.Client = s.Client()
+	c.Delay = 0
+	c.MaxDepth = 0
+	c.IgnoreRobots = true
+
+	docs, err := c.Crawl(context.Background(), s.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range docs {
+		found = found || strings.HasSuffix(d.Source, "/private/map")
+	}
+	if !found {
+		t.Error("the disallowed sitemap page was not crawled with IgnoreRobots")
+	}
+}
+
+func TestRobots(t *testing.T) {
+	r := parseRobots(strings.NewReader("User-agent: other\nDisallow: /\n\nUser-agent: *\nDisallow: /docs/\nAllow: /docs/public\nDisallow: /*.pdf$\nCrawl-delay: 2\n"), DefaultUserAgent)
+	for path, want := range map[string]bool{
+		"/":                            true,
+		"/docs/guide":                  false,
+		"/docs/public/faq":             true,
+		"/files/report.pdf":            false,
+		"/files/report.pdf?download=1": true,
+	} {
+		if got := r.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if r.delay.Seconds() != 2 {
+		t.Errorf("crawl delay is %s, want 2s", r.delay)
+	}
+}
@@ -0,0 +1,132 @@
+package crawl
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots is the part of a robots.txt file that applies to the crawler.
+type robots struct {
+	rules    []robotsRule
+	delay    time.Duration
+	sitemaps []string
+}
+
+// robotsRule allows or disallows the paths matching a pattern.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// parseRobots reads the groups of a robots.txt file that apply to the user
+// agent: the group naming it if there is one, and otherwise the * group.
+// Sitemap lines apply whatever the group.
+func parseRobots(r io.Reader, userAgent string) *robots {
+	agent := strings.ToLower(userAgent)
+	if i := strings.IndexByte(agent, '/'); i >= 0 {
+		agent = agent[:i]
+	}
+
+	var named, wildcard, current *robots
+	inAgents := false
+	sitemaps := []string{}
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := lines.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A run of user-agent lines starts a new group.
+			if !inAgents {
+				current = &robots{}
+				inAgents = true
+			}
+			name := strings.ToLower(value)
+			switch {
+			case name == "*" && wildcard == nil:
+				wildcard = current
+			case name != "*" && named == nil && agent != "" && strings.Contains(agent, name):
+				named = current
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			inAgents = false
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && current != nil {
+				current.delay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		default:
+			inAgents = false
+		}
+	}
+
+	group := named
+	if group == nil {
+		group = wildcard
+	}
+	if group == nil {
+		group = &robots{}
+	}
+	group.sitemaps = sitemaps
+	return group
+}
+
+// allowed reports whether the path, with its query, may be crawled. The
+// longest matching rule wins, and allow wins a tie.
+func (r *robots) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch reports whether a path matches a robots.txt pattern, where *
+// matches any run of characters and a trailing $ anchors the end.
+func robotsMatch(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		j := strings.Index(rest, part)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(part):]
+	}
+	return !anchored || rest == ""
+}
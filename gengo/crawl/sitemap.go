@@ -0,0 +1,61 @@
+package crawl
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxSitemaps is the most sitemap files read for one crawl, counting the
+// ones listed in sitemap indexes.
+const maxSitemaps = 20
+
+// sitemapFile is a sitemap or a sitemap index.
+type sitemapFile struct {
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a location listed in a sitemap.
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapURLs reads the sitemaps and the sitemap indexes they list, and
+// returns the page URLs in them in order. Sitemaps that cannot be read are
+// skipped, since a site does not need one.
+func (s *session) sitemapURLs(ctx context.Context, sitemaps []string) []string {
+	pages := []string{}
+	queue := append([]string{}, sitemaps...)
+	for read := 0; len(queue) > 0 && read < maxSitemaps; read++ {
+		var file sitemapFile
+		if err := s.getXML(ctx, queue[0], &file); err != nil {
+			s.crawler.report(queue[0], err)
+			queue = queue[1:]
+			continue
+		}
+		queue = queue[1:]
+		for _, e := range file.Sitemaps {
+			queue = append(queue, strings.TrimSpace(e.Loc))
+		}
+		for _, e := range file.URLs {
+			pages = append(pages, strings.TrimSpace(e.Loc))
+		}
+	}
+	return pages
+}
+
+// getXML downloads and decodes an XML file.
+func (s *session) getXML(ctx context.Context, rawURL string, v any) error {
+	res, err := s.get(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return statusError(rawURL, res)
+	}
+	return xml.NewDecoder(io.LimitReader(res.Body, maxBody)).Decode(v)
+}
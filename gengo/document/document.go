@@ -0,0 +1,110 @@
+// Package document defines the content that is loaded, split and indexed
+// for retrieval, along with where it came from.
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Formats of document content.
+const (
+	FormatMarkdown = "markdown"
+	FormatText     = "text"
+	FormatGo       = "go"
+)
+
+// Document is a piece of content loaded from a file, a package or a web
+// page.
+type Document struct {
+	// Source is the URI the content was loaded from, like
+	// file:///docs/guide.md or https://go.dev/doc/contribute.
+	Source string `json:"source"`
+
+	Title   string `json:"title,omitempty"`
+	Format  string `json:"format"`
+	Content string `json:"content"`
+
+	// Metadata holds anything else known about the source, like the
+	// file's modification time or a Go package's import path.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Chunk is a piece of a document small enough to embed and retrieve,
+// along with where it came from so answers can cite it.
+type Chunk struct {
+	// ID identifies the chunk by its source, position and content.
+	ID string `json:"id,omitempty"`
+
+	// Source and Title are those of the document the chunk is from.
+	Source string `json:"source,omitempty"`
+	Title  string `json:"title,omitempty"`
+
+	Content string `json:"content"`
+
+	// Headings is the path of Markdown headings the chunk is under, from
+	// the top level down.
+	Headings []string `json:"headings,omitempty"`
+
+	// Start and End are the byte offsets of the chunk in the document's
+	// content, and StartLine and EndLine the lines they are on, counting
+	// from one.
+	Start     int `json:"start,omitempty"`
+	End       int `json:"end,omitempty"`
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+
+	// Ingested is when the chunk was split from its document.
+	Ingested time.Time `json:"ingested"`
+
+	// Hash is the hash of the content, which tells whether it has changed.
+	Hash string `json:"hash,omitempty"`
+
+	// Metadata is a copy of the document's metadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Describe fills in the ID, source, title, line numbers, hash and metadata
+// of a chunk split from the document.
+func (d Document) Describe(c Chunk) Chunk {
+	c.Source = d.Source
+	c.Title = d.Title
+	if c.End <= len(d.Content) && c.Start <= c.End {
+		c.StartLine = 1 + strings.Count(d.Content[:c.Start], "\n")
+		c.EndLine = c.StartLine + strings.Count(strings.TrimSuffix(d.Content[c.Start:c.End], "\n"), "\n")
+	}
+	c.Hash = Hash(c.Content)
+	c.ID = Hash(fmt.Sprintf("%s\x00%d\x00%s", c.Source, c.Start, c.Hash))[:16]
+	if d.Metadata != nil {
+		c.Metadata = make(map[string]string, len(d.Metadata))
+		for k, v := range d.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return c
+}
+
+// Citation describes where the chunk came from, like
+// "file:///docs/guide.md, Install > Linux, lines 12-20".
+func (c Chunk) Citation() string {
+	parts := []string{c.Source}
+	if len(c.Headings) > 0 {
+		parts = append(parts, strings.Join(c.Headings, " > "))
+	}
+	switch {
+	case c.StartLine > 0 && c.EndLine > c.StartLine:
+		parts = append(parts, fmt.Sprintf("lines %d-%d", c.StartLine, c.EndLine))
+	case c.StartLine > 0:
+		parts = append(parts, fmt.Sprintf("line %d", c.StartLine))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Hash returns the hex encoded SHA-256 hash of text.
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
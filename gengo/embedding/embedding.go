@@ -0,0 +1,125 @@
+// Package embedding vectorizes text and compares the resulting vectors.
+package embedding
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	cohere "github.com/cohere-ai/cohere-go"
+)
+
+// DefaultModel is the Cohere model used to embed text.
+const DefaultModel = "embed-english-light-v2.0"
+
+// DefaultBatchSize is the number of texts sent to the embedding API in
+// a single request.
+const DefaultBatchSize = 20
+
+// Embedder vectorizes a batch of texts, returning one vector per text in
+// the same order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// Cohere is an Embedder backed by the Cohere embed endpoint.
+type Cohere struct {
+	Client *cohere.Client
+	Model  string
+}
+
+// NewCohere connects to Cohere with the given API key.
+func NewCohere(apiKey string) (*Cohere, error) {
+	if apiKey == "" {
+		return nil, errors.New("COHERE_API_KEY not specified")
+	}
+	co, err := cohere.CreateClient(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Cohere{Client: co, Model: DefaultModel}, nil
+}
+
+// Embed vectorizes the texts with Cohere.
+func (c *Cohere) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+
+	// The Cohere client does not take a context, so at least avoid
+	// starting a request that has already been cancelled.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := c.Client.Embed(cohere.EmbedOptions{
+		Model: c.Model,
+		Texts: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Embeddings) != len(texts) {
+		return nil, errors.New("cohere returned an unexpected number of embeddings")
+	}
+	return res.Embeddings, nil
+}
+
+// EmbedOne vectorizes a single user message/query.
+func EmbedOne(ctx context.Context, e Embedder, message string) ([]float64, error) {
+	vectors, err := e.Embed(ctx, []string{message})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedAll vectorizes any number of texts, sending them to the Embedder
+// in batches of batchSize or less.
+func EmbedAll(ctx context.Context, e Embedder, texts []string, batchSize int) ([][]float64, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	vectors := make([][]float64, 0, len(texts))
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// CosineSimilarity calculates the cosine similarity between two vectors.
+func CosineSimilarity(a []float64, b []float64) (cosine float64, err error) {
+	count := 0
+	length_a := len(a)
+	length_b := len(b)
+	if length_a > length_b {
+		count = length_a
+	} else {
+		count = length_b
+	}
+	sumA := 0.0
+	s1 := 0.0
+	s2 := 0.0
+	for k := 0; k < count; k++ {
+		if k >= length_a {
+			s2 += math.Pow(b[k], 2)
+			continue
+		}
+		if k >= length_b {
+			s1 += math.Pow(a[k], 2)
+			continue
+		}
+		sumA += a[k] * b[k]
+		s1 += math.Pow(a[k], 2)
+		s2 += math.Pow(b[k], 2)
+	}
+	if s1 == 0 || s2 == 0 {
+		return 0.0, errors.New("vectors should not be null (all zeros)")
+	}
+	return sumA / (math.Sqrt(s1) * math.Sqrt(s2)), nil
+}
@@ -0,0 +1,140 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	cohere "github.com/cohere-ai/cohere-go"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// Middleware adds behavior around an Embedder, like caching, retries or
+// logging.
+type Middleware func(next Embedder) Embedder
+
+// EmbedderFunc adapts a function to an Embedder.
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Embed implements Embedder.
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return f(ctx, texts)
+}
+
+// Chain wraps e in the middleware. The first middleware is the outermost:
+// it sees the texts first and the vectors last.
+func Chain(e Embedder, middleware ...Middleware) Embedder {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		e = middleware[i](e)
+	}
+	return e
+}
+
+// Logging returns middleware that logs the size, latency and outcome of
+// every batch.
+func Logging(logger *log.Logger) Middleware {
+	return func(next Embedder) Embedder {
+		return EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+			start := time.Now()
+			vectors, err := next.Embed(ctx, texts)
+			latency := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logger.Printf("embed texts=%d latency=%s error=%q", len(texts), latency, err)
+				return nil, err
+			}
+			logger.Printf("embed texts=%d latency=%s", len(texts), latency)
+			return vectors, nil
+		})
+	}
+}
+
+// Retry returns middleware that retries batches that failed for reasons
+// that may pass, as reported by Temporary, up to attempts times in all,
+// waiting backoff before the first retry and doubling the wait each time.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next Embedder) Embedder {
+		return EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				vectors, err := next.Embed(ctx, texts)
+				if err == nil || attempt >= attempts || !Temporary(err) {
+					return vectors, err
+				}
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C:
+				}
+				wait *= 2
+			}
+		})
+	}
+}
+
+// Temporary reports whether a batch that failed with err might succeed if
+// retried. It is llm.Temporary, also applied to the status codes of the
+// Cohere API.
+func Temporary(err error) bool {
+	var cohereErr *cohere.APIError
+	if errors.As(err, &cohereErr) {
+		return llm.TemporaryStatus(cohereErr.StatusCode)
+	}
+	return llm.Temporary(err)
+}
+
+// Cache remembers the vector of every text it has seen, so repeated texts
+// are only embedded once.
+type Cache struct {
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+// NewCache returns an empty cache.
+func NewCache() *Cache {
+	return &Cache{vectors: map[string][]float64{}}
+}
+
+// Middleware returns middleware that embeds only the texts missing from
+// the cache.
+func (c *Cache) Middleware(next Embedder) Embedder {
+	return EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		vectors := make([][]float64, len(texts))
+		missing := []string{}
+		positions := map[string][]int{}
+
+		c.mu.Lock()
+		for i, text := range texts {
+			if v, ok := c.vectors[text]; ok {
+				vectors[i] = v
+				continue
+			}
+			if _, ok := positions[text]; !ok {
+				missing = append(missing, text)
+			}
+			positions[text] = append(positions[text], i)
+		}
+		c.mu.Unlock()
+		if len(missing) == 0 {
+			return vectors, nil
+		}
+
+		embedded, err := next.Embed(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		for i, text := range missing {
+			c.vectors[text] = embedded[i]
+			for _, p := range positions[text] {
+				vectors[p] = embedded[i]
+			}
+		}
+		c.mu.Unlock()
+		return vectors, nil
+	})
+}
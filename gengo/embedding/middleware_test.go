@@ -0,0 +1,35 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cohere "github.com/cohere-ai/cohere-go"
+)
+
+func TestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"rate limited", &cohere.APIError{StatusCode: 429}, 3},
+		{"server error", &cohere.APIError{StatusCode: 500}, 3},
+		{"unauthorized", &cohere.APIError{StatusCode: 401}, 1},
+		{"bad request", &cohere.APIError{StatusCode: 400}, 1},
+		{"unknown", errors.New("unexpected number of embeddings"), 1},
+	} {
+		calls := 0
+		e := Retry(3, 0)(EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+			calls++
+			return nil, tc.err
+		}))
+		if _, err := e.Embed(context.Background(), []string{"text"}); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+		if calls != tc.calls {
+			t.Errorf("%s: tried %d times, want %d", tc.name, calls, tc.calls)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for a Pipeline.
+const (
+	DefaultConcurrency = 4
+	DefaultAttempts    = 3
+	DefaultBackoff     = time.Second
+)
+
+// Progress is how far a Pipeline has got.
+type Progress struct {
+	Texts    int `json:"texts"`
+	Embedded int `json:"embedded"`
+	Batches  int `json:"batches"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+}
+
+// BatchError is a batch of texts that could not be embedded.
+type BatchError struct {
+	// Start and End are the indexes of the batch's first text and of the
+	// text after its last.
+	Start int
+	End   int
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("embedding: texts %d to %d: %v", e.Start, e.End-1, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// PartialError is returned by Pipeline.Embed when some batches failed.
+type PartialError struct {
+	Texts    int
+	Embedded int
+	Batches  []*BatchError
+}
+
+// Error implements the error interface.
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("embedding: embedded %d of %d texts, %d batches failed: %v", e.Embedded, e.Texts, len(e.Batches), e.Batches[0].Err)
+}
+
+// Unwrap returns the errors of the failed batches.
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Batches))
+	for i, b := range e.Batches {
+		errs[i] = b
+	}
+	return errs
+}
+
+// Pipeline embeds any number of texts by sending batches to an Embedder
+// from a pool of workers, retrying batches that fail. A Pipeline is itself
+// an Embedder.
+type Pipeline struct {
+	Embedder Embedder
+
+	// BatchSize is the most texts sent in one request, and Concurrency the
+	// most requests in flight.
+	BatchSize   int
+	Concurrency int
+
+	// Attempts is how many times a batch that fails with a Temporary
+	// error is tried in all, waiting Backoff before the first retry and
+	// doubling the wait each time.
+	Attempts int
+	Backoff  time.Duration
+
+	// Progress, if set, is called after every batch, by one goroutine at a
+	// time.
+	Progress func(Progress)
+}
+
+// NewPipeline returns a Pipeline with the default settings.
+func NewPipeline(e Embedder) *Pipeline {
+	return &Pipeline{
+		Embedder:    e,
+		BatchSize:   DefaultBatchSize,
+		Concurrency: DefaultConcurrency,
+		Attempts:    DefaultAttempts,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+// Embed vectorizes the texts, returning one vector per text in the same
+// order. If some batches still fail after their attempts the rest are
+// embedded anyway, and the vectors are returned with nil for the texts
+// that failed, along with a *PartialError. Batches not yet started when
+// the context is cancelled fail with its error.
+func (p *Pipeline) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	batchSize, workers, attempts := p.BatchSize, p.Concurrency, p.Attempts
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+	e := Retry(attempts, p.Backoff)(p.Embedder)
+
+	// Queue the batches.
+	starts := make(chan int)
+	go func() {
+		defer close(starts)
+		for i := 0; i < len(texts); i += batchSize {
+			starts <- i
+		}
+	}()
+
+	// Embed them, putting each vector in its text's place.
+	vectors := make([][]float64, len(texts))
+	progress := Progress{Texts: len(texts), Batches: (len(texts) + batchSize - 1) / batchSize}
+	failed := []*BatchError{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, progress.Batches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range starts {
+				end := min(start+batchSize, len(texts))
+				batch, err := embedBatch(ctx, e, texts[start:end])
+
+				mu.Lock()
+				if err != nil {
+					failed = append(failed, &BatchError{Start: start, End: end, Err: err})
+					progress.Failed++
+				} else {
+					copy(vectors[start:end], batch)
+					progress.Embedded += end - start
+					progress.Done++
+				}
+				if p.Progress != nil {
+					p.Progress(progress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Start < failed[j].Start })
+		return vectors, &PartialError{Texts: len(texts), Embedded: progress.Embedded, Batches: failed}
+	}
+	return vectors, nil
+}
+
+// embedBatch embeds one batch, checking that a vector came back for every
+// text.
+func embedBatch(ctx context.Context, e Embedder, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors, err := e.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, errors.New("embedding: got an unexpected number of vectors")
+	}
+	return vectors, nil
+}
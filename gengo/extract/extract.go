@@ -0,0 +1,255 @@
+// Package extract finds the main content of a web page, leaving out
+// navigation, footers, sidebars and cookie banners, and converts it to
+// Markdown.
+//
+// By default the content is found the way readability tools do, by
+// scoring blocks of text. Rules refine this per site with CSS selectors of
+// the content to keep and the parts to drop, and with start and end
+// markers to trim the Markdown to. A marker or selector that a rule
+// requires but a page lacks is an error rather than an empty page:
+//
+//	e := extract.New(extract.Rule{
+//		Host:    "go.dev",
+//		Include: []string{"main"},
+//		Exclude: []string{".Article-toc"},
+//		Start:   "# Contribution Guide",
+//	})
+//	content, err := e.ExtractString("https://go.dev/doc/contribute", html)
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule says how to extract the content of a site's pages.
+type Rule struct {
+	// Host is the host the rule applies to, like "go.dev", or a pattern
+	// like "*.go.dev". An empty host matches every page.
+	Host string `yaml:"host"`
+
+	// PathPrefix limits the rule to pages whose path starts with it.
+	PathPrefix string `yaml:"path_prefix"`
+
+	// Include holds CSS selectors of the elements with the content. If
+	// empty, the content is found by scoring the page.
+	Include []string `yaml:"include"`
+
+	// Exclude holds CSS selectors of elements to drop from the content.
+	Exclude []string `yaml:"exclude"`
+
+	// Start and End trim the Markdown to the text after the first Start
+	// and before the first End that follows it.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// matches reports whether the rule applies to a page.
+func (r Rule) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	switch pattern := strings.ToLower(r.Host); {
+	case pattern == "":
+	case strings.HasPrefix(pattern, "*."):
+		if host != pattern[2:] && !strings.HasSuffix(host, pattern[1:]) {
+			return false
+		}
+	case host != pattern:
+		return false
+	}
+	return strings.HasPrefix(u.Path, r.PathPrefix)
+}
+
+// LoadRules reads a YAML file holding a list of rules.
+func LoadRules(file string) ([]Rule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return rules, nil
+}
+
+// ErrNotFound is matched by a *NotFoundError with errors.Is.
+var ErrNotFound = errors.New("extract: not found")
+
+// ErrNoContent is returned when a page has no text left after extraction.
+var ErrNoContent = errors.New("extract: no content")
+
+// NotFoundError is returned when a page lacks a marker or selector that
+// its rule requires.
+type NotFoundError struct {
+	URL string
+
+	// What is "start marker", "end marker" or "include selector".
+	What  string
+	Value string
+}
+
+// Error implements the error interface.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("extract: %s: %s %q not found", e.URL, e.What, e.Value)
+}
+
+// Is reports whether target is ErrNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Content is the extracted content of a page.
+type Content struct {
+	Title    string
+	Markdown string
+}
+
+// Extractor extracts the content of pages.
+type Extractor struct {
+	// Rules are tried in order, and the first that matches a page is
+	// used. Pages no rule matches are scored.
+	Rules []Rule
+}
+
+// New returns an extractor with the rules.
+func New(rules ...Rule) *Extractor {
+	return &Extractor{Rules: rules}
+}
+
+// rule returns the first rule that matches the page.
+func (e *Extractor) rule(pageURL string) Rule {
+	u, err := url.Parse(pageURL)
+	if err != nil || e == nil {
+		return Rule{}
+	}
+	for _, r := range e.Rules {
+		if r.matches(u) {
+			return r
+		}
+	}
+	return Rule{}
+}
+
+// ExtractString extracts the content of a page from its HTML.
+func (e *Extractor) ExtractString(pageURL string, html string) (*Content, error) {
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	if base, err := url.Parse(pageURL); err == nil {
+		ResolveLinks(page, base)
+	}
+	return e.Extract(pageURL, page)
+}
+
+// Extract extracts the content of a parsed page. The page is modified:
+// the parts that are not content are removed from it. Relative links
+// should already have been made absolute with ResolveLinks.
+func (e *Extractor) Extract(pageURL string, page *goquery.Document) (*Content, error) {
+	rule := e.rule(pageURL)
+	content := Content{Title: title(page)}
+
+	if len(rule.Exclude) > 0 {
+		page.Find(strings.Join(rule.Exclude, ", ")).Remove()
+	}
+	var selections []*goquery.Selection
+	if len(rule.Include) > 0 {
+		for _, selector := range rule.Include {
+			s := page.Find(selector)
+			if s.Length() == 0 {
+				return nil, &NotFoundError{URL: pageURL, What: "include selector", Value: selector}
+			}
+			selections = append(selections, outermost(s))
+		}
+	} else {
+		selections = []*goquery.Selection{mainContent(page)}
+	}
+
+	domain := md.DomainFromURL(pageURL)
+	parts := []string{}
+	for _, s := range selections {
+		s.Each(func(_ int, el *goquery.Selection) {
+			if text := strings.TrimSpace(md.NewConverter(domain, true, nil).Convert(el)); text != "" {
+				parts = append(parts, text)
+			}
+		})
+	}
+	markdown, err := trim(strings.Join(parts, "\n\n"), rule.Start, rule.End)
+	if err != nil {
+		err.URL = pageURL
+		return nil, err
+	}
+	content.Markdown = strings.TrimSpace(markdown)
+	if content.Markdown == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoContent, pageURL)
+	}
+	return &content, nil
+}
+
+// trim returns the text after the first start and before the first end
+// that follows it. Empty markers are ignored.
+func trim(text string, start string, end string) (string, *NotFoundError) {
+	if start != "" {
+		_, after, ok := strings.Cut(text, start)
+		if !ok {
+			return "", &NotFoundError{What: "start marker", Value: start}
+		}
+		text = after
+	}
+	if end != "" {
+		before, _, ok := strings.Cut(text, end)
+		if !ok {
+			return "", &NotFoundError{What: "end marker", Value: end}
+		}
+		text = before
+	}
+	return text, nil
+}
+
+// title returns the page's <title>, or its first level one heading.
+func title(page *goquery.Document) string {
+	if t := strings.TrimSpace(page.Find("title").First().Text()); t != "" {
+		return t
+	}
+	return strings.Join(strings.Fields(page.Find("h1").First().Text()), " ")
+}
+
+// outermost drops the elements of a selection that are inside another of
+// its elements, so their content is not converted twice.
+func outermost(s *goquery.Selection) *goquery.Selection {
+	return s.FilterFunction(func(_ int, el *goquery.Selection) bool {
+		return el.ParentsFiltered("*").FilterSelection(s).Length() == 0
+	})
+}
+
+// ResolveLinks makes the links and image sources of a page absolute,
+// relative to its <base> or else base, since the Markdown converter only
+// knows the page's domain.
+func ResolveLinks(page *goquery.Document, base *url.URL) {
+	if href, ok := page.Find("base[href]").Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+	page.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			a.SetAttr("href", u.String())
+		}
+	})
+	page.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		src, _ := img.Attr("src")
+		if u, err := base.Parse(strings.TrimSpace(src)); err == nil {
+			img.SetAttr("src", u.String())
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// boilerplate selects elements that are never content.
+const boilerplate = `script, style, noscript, template, iframe, svg, form, button, nav, aside, dialog,
+	[role="navigation"], [role="banner"], [role="contentinfo"], [role="complementary"], [role="dialog"],
+	[aria-hidden="true"], [hidden]`
+
+// Class and id patterns of elements that are unlikely and likely to hold
+// the content.
+var (
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|consent|cookie|disqus|extra|foot|gdpr|header|menu|modal|nav|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skip|social|sponsor|subscribe|toolbar|tweet`)
+	likely   = regexp.MustCompile(`(?i)and|article|body|column|content|main|post|shadow|text`)
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negative = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|foot|footer|masthead|media|meta|promo|related|scroll|share|shoutbox|sidebar|sponsor|tags|widget`)
+)
+
+// minArticleText is the least text, in bytes, for an <article> or <main>
+// element to be taken as the content without scoring.
+const minArticleText = 250
+
+// mainContent returns the element of a page most likely to hold its main
+// content, after removing the elements that are not. Pages with an
+// <article> or <main> element with enough text use the one with the most
+// text. Other pages are scored: every paragraph adds to its parent and
+// half to its grandparent a score for its length and commas, weighted by
+// their classes, and the element with the highest score less the share
+// of its text in links is picked.
+func mainContent(page *goquery.Document) *goquery.Selection {
+	page.Find(boilerplate).Remove()
+	page.Find("header, footer").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("article, main").Length() == 0 {
+			s.Remove()
+		}
+	})
+	page.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "article" || goquery.NodeName(s) == "main" {
+			return
+		}
+		names := classAndID(s)
+		if unlikely.MatchString(names) && !likely.MatchString(names) {
+			s.Remove()
+		}
+	})
+
+	// Use a marked up article if there is one.
+	var best *goquery.Selection
+	bestLength := minArticleText
+	page.Find(`article, main, [role="main"]`).Each(func(_ int, s *goquery.Selection) {
+		if n := len(strings.TrimSpace(s.Text())); n >= bestLength {
+			best, bestLength = s, n
+		}
+	})
+	if best != nil {
+		return best
+	}
+
+	// Score the parents of the paragraphs.
+	scores := map[*html.Node]float64{}
+	candidates := []*goquery.Selection{}
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 || goquery.NodeName(s) == "html" {
+			return
+		}
+		node := s.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += score
+	}
+	page.Find("p, pre, td, blockquote, li").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(p.Parent(), score)
+		addScore(p.Parent().Parent(), score/2)
+	})
+
+	bestScore := 0.0
+	for _, s := range candidates {
+		score := scores[s.Get(0)] * (1 - linkDensity(s))
+		if best == nil || score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	if best == nil {
+		return page.Find("body")
+	}
+	return best
+}
+
+// initialScore is the score of an element before its paragraphs are
+// counted, from its tag and its class and id.
+func initialScore(s *goquery.Selection) float64 {
+	score := 0.0
+	switch goquery.NodeName(s) {
+	case "div", "section", "article", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "ol", "ul", "dl", "dd", "dt", "li", "address", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	names := classAndID(s)
+	if positive.MatchString(names) {
+		score += 25
+	}
+	if negative.MatchString(names) {
+		score -= 25
+	}
+	return score
+}
+
+// linkDensity is the fraction of an element's text that is in links.
+func linkDensity(s *goquery.Selection) float64 {
+	text := len(strings.TrimSpace(s.Text()))
+	if text == 0 {
+		return 0
+	}
+	links := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(links) / float64(text)
+}
+
+// classAndID returns an element's class and id for matching against the
+// patterns.
+func classAndID(s *goquery.Selection) string {
+	class, _ := s.Attr("class")
+	id, _ := s.Attr("id")
+	return class + " " + id
+}
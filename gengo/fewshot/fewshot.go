@@ -0,0 +1,171 @@
+// Package fewshot assembles few-shot prompts from the labeled examples
+// that are most similar to the current input.
+package fewshot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// Example is a labeled input/output pair shown to the model.
+type Example struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// Builder holds an embedded pool of examples and builds prompts from the
+// K examples closest to an input.
+type Builder struct {
+
+	// Instruction is placed at the top of every prompt.
+	Instruction string
+
+	// InputPrefix and OutputPrefix label each example, e.g. "Text: " and
+	// "Sentiment: ".
+	InputPrefix  string
+	OutputPrefix string
+
+	// K is the maximum number of examples to include. Zero or less means
+	// no limit other than MaxTokens.
+	K int
+
+	// MaxTokens bounds the size of the whole prompt. Zero means no limit.
+	MaxTokens int
+
+	// CountTokens measures text against MaxTokens. It defaults to
+	// tokens.Count.
+	CountTokens func(string) int
+
+	embedder embedding.Embedder
+	examples []Example
+	vectors  [][]float64
+}
+
+// NewBuilder embeds the pool of examples so they can be selected later.
+func NewBuilder(ctx context.Context, e embedding.Embedder, examples []Example) (*Builder, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("fewshot: no examples provided")
+	}
+
+	// Embed the inputs of the examples, which is what we compare against.
+	inputs := make([]string, len(examples))
+	for i, ex := range examples {
+		inputs[i] = ex.Input
+	}
+	vectors, err := embedding.EmbedAll(ctx, e, inputs, embedding.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	b := Builder{
+		InputPrefix:  "Input: ",
+		OutputPrefix: "Output: ",
+		K:            5,
+		CountTokens:  tokens.Count,
+		embedder:     e,
+		examples:     examples,
+		vectors:      vectors,
+	}
+	return &b, nil
+}
+
+// scoredExample is an example with its similarity to the current input.
+type scoredExample struct {
+	example    Example
+	similarity float64
+}
+
+// Select returns the examples most similar to the input that fit within
+// the token budget, up to K of them if K is positive, ordered from least
+// to most similar so that the closest example ends up right before the
+// input.
+func (b *Builder) Select(ctx context.Context, input string) ([]Example, error) {
+
+	// Embed the input.
+	vector, err := embedding.EmbedOne(ctx, b.embedder, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Score every example in the pool.
+	scored := make([]scoredExample, len(b.examples))
+	for i, ex := range b.examples {
+		similarity, err := embedding.CosineSimilarity(b.vectors[i], vector)
+		if err != nil {
+			return nil, err
+		}
+		scored[i] = scoredExample{example: ex, similarity: similarity}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].similarity > scored[j].similarity
+	})
+
+	// Greedily take the most similar examples while they fit the budget.
+	used := b.countTokens(b.formatInstruction() + b.formatQuery(input))
+	selected := []Example{}
+	for _, s := range scored {
+		if b.K > 0 && len(selected) == b.K {
+			break
+		}
+		cost := b.countTokens(b.formatExample(s.example))
+		if b.MaxTokens > 0 && used+cost > b.MaxTokens {
+			continue
+		}
+		used += cost
+		selected = append(selected, s.example)
+	}
+
+	// Reverse so the most similar example is last.
+	for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+		selected[i], selected[j] = selected[j], selected[i]
+	}
+	return selected, nil
+}
+
+// Prompt assembles a few-shot prompt for the input.
+func (b *Builder) Prompt(ctx context.Context, input string) (string, error) {
+	selected, err := b.Select(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(b.formatInstruction())
+	for _, ex := range selected {
+		sb.WriteString(b.formatExample(ex))
+	}
+	sb.WriteString(b.formatQuery(input))
+	return sb.String(), nil
+}
+
+// formatInstruction renders the instruction and the blank line after it,
+// or nothing if there is no instruction.
+func (b *Builder) formatInstruction() string {
+	if b.Instruction == "" {
+		return ""
+	}
+	return b.Instruction + "\n\n"
+}
+
+// formatExample renders a single labeled example.
+func (b *Builder) formatExample(ex Example) string {
+	return b.InputPrefix + ex.Input + "\n" + b.OutputPrefix + ex.Output + "\n\n"
+}
+
+// formatQuery renders the unlabeled input the model should complete.
+func (b *Builder) formatQuery(input string) string {
+	return b.InputPrefix + input + "\n" + b.OutputPrefix
+}
+
+// countTokens measures text with the configured counter.
+func (b *Builder) countTokens(text string) int {
+	if b.CountTokens == nil {
+		return tokens.Count(text)
+	}
+	return b.CountTokens(text)
+}
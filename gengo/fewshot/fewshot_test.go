@@ -0,0 +1,121 @@
+package fewshot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// topics are the words the fake embedder places inputs by.
+var topics = []string{"refund", "shipping", "password", "invoice"}
+
+// topicEmbedder embeds texts by how often they mention each topic, so
+// examples about the input's topic are the most similar.
+var topicEmbedder = embedding.EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		v := []float64{0.1}
+		for _, topic := range topics {
+			v = append(v, float64(strings.Count(text, topic)))
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+})
+
+// examples are a pool of support requests and their labels.
+var examples = []Example{
+	{Input: "where is my refund", Output: "refund"},
+	{Input: "refund refund please", Output: "refund"},
+	{Input: "shipping is late", Output: "shipping"},
+	{Input: "reset my password", Output: "account"},
+	{Input: "send the invoice again", Output: "billing"},
+}
+
+// countWords counts whitespace separated words, so budgets in tests do not
+// depend on the tokenizer.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	b, err := NewBuilder(ctx, topicEmbedder, examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Instruction = "Label the request."
+	b.CountTokens = countWords
+
+	// The instruction and the query "Input: refund for my order\nOutput: "
+	// are 9 words, and each example 2 for its prefixes plus its words:
+	// 7 for "where is my refund", the most similar, and 6 for "refund
+	// refund please", the next.
+	for _, tc := range []struct {
+		name      string
+		k         int
+		maxTokens int
+		want      []string
+	}{
+		{"k", 2, 0, []string{"refund refund please", "where is my refund"}},
+		{"no k", 0, 0, []string{"send the invoice again", "reset my password", "shipping is late", "refund refund please", "where is my refund"}},
+		{"budget", 0, 9 + 7 + 6, []string{"refund refund please", "where is my refund"}},
+		{"budget skips large examples", 0, 9 + 6, []string{"refund refund please"}},
+		{"budget too small", 3, 9, []string{}},
+	} {
+		b.K, b.MaxTokens = tc.k, tc.maxTokens
+		selected, err := b.Select(ctx, "refund for my order")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, ex := range selected {
+			got = append(got, ex.Input)
+		}
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%s: selected %q, want %q", tc.name, got, tc.want)
+		}
+		if tc.maxTokens > 0 {
+			prompt, err := b.Prompt(ctx, "refund for my order")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := countWords(prompt); n > tc.maxTokens {
+				t.Errorf("%s: prompt has %d tokens, more than %d", tc.name, n, tc.maxTokens)
+			}
+		}
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	ctx := context.Background()
+	b, err := NewBuilder(ctx, topicEmbedder, examples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.K = 1
+	b.InputPrefix, b.OutputPrefix = "Text: ", "Label: "
+
+	want := "Text: where is my refund\nLabel: refund\n\nText: my refund\nLabel: "
+	for _, instruction := range []string{"", "Label the request."} {
+		b.Instruction = instruction
+		if instruction != "" {
+			want = instruction + "\n\n" + want
+		}
+		prompt, err := b.Prompt(ctx, "my refund")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prompt != want {
+			t.Errorf("got prompt %q, want %q", prompt, want)
+		}
+	}
+}
+
+func TestNewBuilderNoExamples(t *testing.T) {
+	if _, err := NewBuilder(context.Background(), topicEmbedder, nil); err == nil {
+		t.Error("built with no examples")
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/predictionguard/gophercon-gen-ai/gengo
+
+go 1.21.1
+
+require (
+	github.com/JohannesKaufmann/html-to-markdown v1.4.1
+	github.com/PuerkitoBio/goquery v1.9.2
+	github.com/cohere-ai/cohere-go v0.2.0
+	github.com/cohere-ai/tokenizer v1.1.1
+	github.com/ledongthuc/pdf v0.0.0-20240201131950-da5b75280b06
+	golang.org/x/net v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/andybalholm/cascadia v1.3.2 // indirect
+	github.com/dlclark/regexp2 v1.4.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+)
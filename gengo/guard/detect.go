@@ -0,0 +1,142 @@
+package guard
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of finding reported by the built-in detectors.
+const (
+	KindEmail      = "email"
+	KindPhone      = "phone"
+	KindCreditCard = "credit_card"
+	KindAPIKey     = "api_key"
+	KindToxicity   = "toxicity"
+)
+
+// Finding is a piece of text a detector flagged.
+type Finding struct {
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Detector finds sensitive or unwanted text.
+type Detector interface {
+	Detect(text string) []Finding
+}
+
+// Pattern detects text matching a regular expression. If Valid is set,
+// matches it rejects are ignored.
+type Pattern struct {
+	Kind  string
+	Regex *regexp.Regexp
+	Valid func(match string) bool
+}
+
+// Detect implements Detector.
+func (p Pattern) Detect(text string) []Finding {
+	findings := []Finding{}
+	for _, loc := range p.Regex.FindAllStringIndex(text, -1) {
+		match := text[loc[0]:loc[1]]
+		if p.Valid != nil && !p.Valid(match) {
+			continue
+		}
+		findings = append(findings, Finding{Kind: p.Kind, Text: match, Start: loc[0], End: loc[1]})
+	}
+	return findings
+}
+
+// Email detects email addresses.
+var Email = Pattern{
+	Kind:  KindEmail,
+	Regex: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+}
+
+// Phone detects phone numbers written with an area code, like
+// (555) 123-4567, 555.123.4567 or +1 555 123 4567.
+var Phone = Pattern{
+	Kind:  KindPhone,
+	Regex: regexp.MustCompile(`(\+\d{1,3}[\s.-]?)?(\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`),
+}
+
+// CreditCard detects card numbers of 13 to 19 digits, optionally grouped
+// with spaces or dashes, that pass the Luhn check.
+var CreditCard = Pattern{
+	Kind:  KindCreditCard,
+	Regex: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	Valid: luhn,
+}
+
+// APIKey detects well known API key and token formats, and values
+// assigned to names like api_key, secret or token.
+var APIKey = Pattern{
+	Kind: KindAPIKey,
+	Regex: regexp.MustCompile(`\b(sk-[A-Za-z0-9_-]{20,}` +
+		`|AKIA[0-9A-Z]{16}` +
+		`|gh[pousr]_[A-Za-z0-9]{36,}` +
+		`|xox[abposr]-[A-Za-z0-9-]{10,}` +
+		`|AIza[0-9A-Za-z_-]{35})` +
+		`|(?i:api[_-]?key|secret|token)["']?\s*[:=]\s*["']?[A-Za-z0-9_\-./+]{16,}`),
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if !unicode.IsDigit(rune(s[i])) {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Lexicon detects any of a list of words or phrases, ignoring case.
+type Lexicon struct {
+	Kind  string
+	regex *regexp.Regexp
+}
+
+// NewLexicon returns a detector for the words, reported as kind.
+func NewLexicon(kind string, words []string) *Lexicon {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSpace(w)), " ", `\s+`)
+	}
+
+	// Try longer phrases first so "shut up" is not reported as "shut".
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return &Lexicon{
+		Kind:  kind,
+		regex: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+	}
+}
+
+// Detect implements Detector.
+func (l *Lexicon) Detect(text string) []Finding {
+	return Pattern{Kind: l.Kind, Regex: l.regex}.Detect(text)
+}
+
+// ToxicWords is a small default lexicon of insults and abuse. It catches
+// the obvious cases only; pass a larger list to NewLexicon for real use.
+var ToxicWords = []string{
+	"idiot", "idiots", "idiotic", "moron", "morons", "stupid", "imbecile",
+	"dumbass", "loser", "losers", "pathetic", "worthless", "scum",
+	"shut up", "screw you", "go to hell", "i hate you", "kill yourself",
+	"piece of crap", "piece of shit", "shit", "bullshit", "fuck", "fucking",
+	"fucked", "asshole", "bastard", "bitch", "dickhead", "crap",
+}
+
+// Toxicity detects the default lexicon of toxic words.
+var Toxicity = NewLexicon(KindToxicity, ToxicWords)
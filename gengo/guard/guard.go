@@ -0,0 +1,213 @@
+// Package guard scans prompts and completions locally, without calling
+// the Prediction Guard API, for personal information (emails, phone
+// numbers, credit cards, API keys) and toxic language, and blocks, redacts
+// or warns about what it finds.
+//
+// A Guard wraps any llm.Completer, so it can be put in front of the client
+// used by every example, alone or as llm.Middleware:
+//
+//	var c llm.Completer = guard.Default().Wrap(llm.NewClient(""))
+//	c = llm.Chain(llm.NewClient(""), guard.Default().Wrap, llm.Retry(3, time.Second))
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// Action is what a guard does with a finding.
+type Action string
+
+// Actions.
+const (
+	Block  Action = "block"
+	Redact Action = "redact"
+	Warn   Action = "warn"
+)
+
+// Rule applies an action to everything a detector finds.
+type Rule struct {
+	Detector Detector
+	Action   Action
+}
+
+// ErrBlocked is matched by a *BlockedError with errors.Is.
+var ErrBlocked = errors.New("guard: blocked")
+
+// BlockedError is returned when text contains a finding whose action is
+// Block.
+type BlockedError struct {
+	// Stage is "prompt" or "completion".
+	Stage    string
+	Findings []Finding
+}
+
+// Error implements the error interface.
+func (e *BlockedError) Error() string {
+	kinds := []string{}
+	for _, f := range e.Findings {
+		kinds = append(kinds, f.Kind)
+	}
+	return fmt.Sprintf("guard: %s blocked: contains %s", e.Stage, strings.Join(unique(kinds), ", "))
+}
+
+// Is reports whether target is ErrBlocked.
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// Guard checks text against a set of rules.
+type Guard struct {
+	Rules []Rule
+
+	// Warn is called with the findings whose action is Warn. If nil they
+	// are logged.
+	Warn func(stage string, f Finding)
+}
+
+// New returns a guard that applies the rules.
+func New(rules ...Rule) *Guard {
+	return &Guard{Rules: rules}
+}
+
+// Default returns a guard that redacts personal information, blocks API
+// keys and blocks toxic language.
+func Default() *Guard {
+	return New(
+		Rule{Detector: Email, Action: Redact},
+		Rule{Detector: Phone, Action: Redact},
+		Rule{Detector: CreditCard, Action: Redact},
+		Rule{Detector: APIKey, Action: Block},
+		Rule{Detector: Toxicity, Action: Block},
+	)
+}
+
+// Scan returns everything the rules' detectors find in text, in order.
+func (g *Guard) Scan(text string) []Finding {
+	findings := []Finding{}
+	for _, r := range g.Rules {
+		findings = append(findings, r.Detector.Detect(text)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// Check applies the rules to text from the named stage. It returns a
+// *BlockedError if anything is blocked, and otherwise the text with the
+// findings to redact replaced by their kind, like "[EMAIL]".
+func (g *Guard) Check(stage string, text string) (string, error) {
+	var blocked, redact []Finding
+	for _, r := range g.Rules {
+		for _, f := range r.Detector.Detect(text) {
+			switch r.Action {
+			case Block:
+				blocked = append(blocked, f)
+			case Redact:
+				redact = append(redact, f)
+			default:
+				g.warn(stage, f)
+			}
+		}
+	}
+	if len(blocked) > 0 {
+		return "", &BlockedError{Stage: stage, Findings: blocked}
+	}
+	return redacted(text, redact), nil
+}
+
+// warn reports a finding whose action is Warn.
+func (g *Guard) warn(stage string, f Finding) {
+	if g.Warn != nil {
+		g.Warn(stage, f)
+		return
+	}
+	log.Printf("guard: %s contains %s", stage, f.Kind)
+}
+
+// redacted replaces the findings in text with placeholders. Findings that
+// overlap an earlier one are dropped.
+func redacted(text string, findings []Finding) string {
+	if len(findings) == 0 {
+		return text
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		if f.Start < last {
+			continue
+		}
+		b.WriteString(text[last:f.Start])
+		b.WriteString("[" + strings.ToUpper(f.Kind) + "]")
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// unique removes repeated strings, keeping the first of each.
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Wrap returns a completer that checks prompts before passing them to
+// next and checks the completions it returns. The typed output value of a
+// redacted completion is parsed again from the redacted text.
+func (g *Guard) Wrap(next llm.Completer) llm.Completer {
+	return completer{guard: g, next: next}
+}
+
+// completer applies a guard around another completer.
+type completer struct {
+	guard *Guard
+	next  llm.Completer
+}
+
+// Complete implements llm.Completer.
+func (c completer) Complete(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResults, error) {
+	prompt, err := c.guard.Check("prompt", request.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	request.Prompt = prompt
+
+	response, err := c.next.Complete(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		text, err := c.guard.Check("completion", choice.Text)
+		if err != nil {
+			return nil, err
+		}
+		if text == choice.Text {
+			continue
+		}
+
+		// The typed value was parsed from the text before it was redacted,
+		// so parse it again from the redacted text, or drop it.
+		choice.Text = text
+		if choice.Value != nil {
+			if choice.Value, err = llm.ParseOutput(request.Output, llm.TrimStop(text, request.Stop)); err != nil {
+				choice.Value = nil
+			}
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,67 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+func TestCheck(t *testing.T) {
+	var warned []string
+	g := New(
+		Rule{Detector: Email, Action: Redact},
+		Rule{Detector: Phone, Action: Redact},
+		Rule{Detector: CreditCard, Action: Warn},
+		Rule{Detector: Toxicity, Action: Block},
+	)
+	g.Warn = func(stage string, f Finding) { warned = append(warned, stage+" "+f.Kind) }
+
+	for _, tc := range []struct {
+		name    string
+		text    string
+		want    string
+		blocked bool
+		warned  int
+	}{
+		{"clean", "What is a goroutine?", "What is a goroutine?", false, 0},
+		{"redacted", "Mail jo@example.com or call (555) 123-4567.", "Mail [EMAIL] or call [PHONE].", false, 0},
+		{"warned", "My card is 4111 1111 1111 1111.", "My card is 4111 1111 1111 1111.", false, 1},
+		{"blocked", "Mail jo@example.com, you idiot.", "", true, 0},
+	} {
+		warned = nil
+		got, err := g.Check("prompt", tc.text)
+		if tc.blocked != errors.Is(err, ErrBlocked) {
+			t.Errorf("%s: got error %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+		if len(warned) != tc.warned {
+			t.Errorf("%s: warned %q", tc.name, warned)
+		}
+	}
+}
+
+func TestWrapReparsesRedactedValues(t *testing.T) {
+	next := llm.CompleterFunc(func(ctx context.Context, request llm.CompletionRequest) (*llm.CompletionResults, error) {
+		text := "Call 555-123-4567, the answer is 42."
+		value, err := llm.ParseOutput(request.Output, text)
+		if err != nil {
+			return nil, err
+		}
+		return &llm.CompletionResults{Choices: []llm.CompletionResult{{Text: text, Value: value}}}, nil
+	})
+	response, err := Default().Wrap(next).Complete(context.Background(), llm.CompletionRequest{
+		Prompt: "What is the answer?",
+		Output: llm.TypedOutput{Type: llm.OutputInteger},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	choice := response.Choices[0]
+	if choice.Text != "Call [PHONE], the answer is 42." || choice.Value != int64(42) {
+		t.Errorf("got text %q and value %v", choice.Text, choice.Value)
+	}
+}
@@ -0,0 +1,259 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// Manifest records what an index was built from, so it can be updated
+// without embedding again what has not changed. It is kept next to the
+// index, at ManifestPath.
+type Manifest struct {
+	// Split is how the documents were split. When it changes, every
+	// document is split again.
+	Split   split.Config `json:"split"`
+	Updated time.Time    `json:"updated"`
+
+	// Documents holds the indexed documents by source.
+	Documents map[string]ManifestEntry `json:"documents"`
+}
+
+// ManifestEntry is a document in an index.
+type ManifestEntry struct {
+	// Hash is the hash of the document's title, format and content.
+	Hash    string    `json:"hash"`
+	Indexed time.Time `json:"indexed"`
+
+	// Chunks holds the IDs of the document's chunks, in order.
+	Chunks []string `json:"chunks"`
+}
+
+// ManifestPath returns the path of the manifest of the index at path, like
+// chunks.manifest.json for chunks.json.
+func ManifestPath(index string) string {
+	ext := filepath.Ext(index)
+	return strings.TrimSuffix(index, ext) + ".manifest" + ext
+}
+
+// LoadManifest reads a manifest from a JSON file. A missing file is an
+// empty manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := Manifest{Documents: map[string]ManifestEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Documents == nil {
+		m.Documents = map[string]ManifestEntry{}
+	}
+	return &m, nil
+}
+
+// Save writes the manifest to a JSON file.
+func (m *Manifest) Save(path string) error {
+	outJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, outJSON, 0644)
+}
+
+// Indexer keeps an index of vectorized chunks up to date with the
+// documents it is built from.
+type Indexer struct {
+	// Path is the index file.
+	Path  string
+	Split split.Config
+
+	// Embedder embeds the new chunks. If it is not an embedding.Pipeline,
+	// a Pipeline with the default settings is put around it.
+	Embedder embedding.Embedder
+
+	// Rebuild ignores the existing index and embeds every chunk again, as
+	// is needed after changing the embedding model.
+	Rebuild bool
+}
+
+// IndexStats reports what an update of an index did.
+type IndexStats struct {
+	Documents int `json:"documents"`
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+
+	// Chunks is the number of chunks in the index, and Embedded the
+	// number of them that had to be embedded. Failed counts the chunks
+	// left out because embedding them failed.
+	Chunks   int `json:"chunks"`
+	Embedded int `json:"embedded"`
+	Failed   int `json:"failed,omitempty"`
+}
+
+// Update makes the index hold the chunks of exactly the given documents
+// and saves it along with its manifest. Documents that have not changed
+// keep their chunks, chunks whose content is already in the index keep
+// their vectors, and chunks of removed documents are dropped, so updating
+// an index with an unchanged corpus makes no embedding calls.
+//
+// If some chunks cannot be embedded, the index is saved without them and
+// without their documents in the manifest, so those documents are tried
+// again by the next update, and the stats are returned along with the
+// *embedding.PartialError.
+func (ix *Indexer) Update(ctx context.Context, docs []document.Document) (*IndexStats, error) {
+	splitter, err := split.New(ix.Split, ix.Embedder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read what was indexed before.
+	old := vectorstore.VectorizedChunks{}
+	manifest := &Manifest{Documents: map[string]ManifestEntry{}}
+	if !ix.Rebuild {
+		if old, err = vectorstore.Load(ix.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if manifest, err = LoadManifest(ManifestPath(ix.Path)); err != nil {
+			return nil, err
+		}
+	}
+	byID := map[string]vectorstore.VectorizedChunk{}
+	vectors := map[string][]float64{}
+	for _, c := range old {
+		byID[c.ID] = c
+		if c.Hash == "" {
+			c.Hash = document.Hash(c.Content)
+		}
+		vectors[c.Hash] = c.Vector
+	}
+
+	// Keep the chunks of unchanged documents and split the rest, noting
+	// the chunks whose content has no vector yet.
+	stats := IndexStats{}
+	now := time.Now().UTC()
+	next := &Manifest{Split: ix.Split, Updated: now, Documents: map[string]ManifestEntry{}}
+	index := vectorstore.VectorizedChunks{}
+	missing := map[string][]int{}
+	texts := []string{}
+	for _, d := range docs {
+		if _, ok := next.Documents[d.Source]; ok {
+			continue
+		}
+		stats.Documents++
+		hash := documentHash(d)
+		entry, ok := manifest.Documents[d.Source]
+		if ok && entry.Hash == hash && manifest.Split == ix.Split {
+			if kept, ok := lookup(byID, entry.Chunks); ok {
+				index = append(index, kept...)
+				next.Documents[d.Source] = entry
+				stats.Unchanged++
+				continue
+			}
+		}
+		if ok {
+			stats.Changed++
+		} else {
+			stats.Added++
+		}
+
+		chunks, err := DocumentChunks(ctx, splitter, []document.Document{d})
+		if err != nil {
+			return nil, err
+		}
+		entry = ManifestEntry{Hash: hash, Indexed: now, Chunks: []string{}}
+		for _, c := range chunks {
+			entry.Chunks = append(entry.Chunks, c.ID)
+			v := vectorstore.VectorizedChunk{Chunk: c, Vector: vectors[c.Hash]}
+			if v.Vector == nil {
+				if _, ok := missing[c.Hash]; !ok {
+					texts = append(texts, c.Content)
+				}
+				missing[c.Hash] = append(missing[c.Hash], len(index))
+			}
+			index = append(index, v)
+		}
+		next.Documents[d.Source] = entry
+	}
+	for source := range manifest.Documents {
+		if _, ok := next.Documents[source]; !ok {
+			stats.Removed++
+		}
+	}
+
+	// Embed the new content. Chunks that could not be embedded are left
+	// out, along with their documents.
+	embedded, err := pipeline(ix.Embedder).Embed(ctx, texts)
+	var partial *embedding.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	for i, text := range texts {
+		for _, p := range missing[document.Hash(text)] {
+			index[p].Vector = embedded[i]
+		}
+		if embedded[i] != nil {
+			stats.Embedded++
+		}
+	}
+	if partial != nil {
+		kept := vectorstore.VectorizedChunks{}
+		for _, c := range index {
+			if c.Vector == nil {
+				delete(next.Documents, c.Source)
+				stats.Failed++
+				continue
+			}
+			kept = append(kept, c)
+		}
+		index = kept
+	}
+	stats.Chunks = len(index)
+
+	// Save the index before the manifest, so a manifest never lists chunks
+	// the index does not have.
+	if err := index.Save(ix.Path); err != nil {
+		return nil, err
+	}
+	if err := next.Save(ManifestPath(ix.Path)); err != nil {
+		return nil, err
+	}
+	if partial != nil {
+		return &stats, partial
+	}
+	return &stats, nil
+}
+
+// documentHash identifies the version of a document that was indexed.
+func documentHash(d document.Document) string {
+	return document.Hash(d.Format + "\x00" + d.Title + "\x00" + d.Content)
+}
+
+// lookup returns the chunks with the IDs, or false if any is missing.
+func lookup(byID map[string]vectorstore.VectorizedChunk, ids []string) (vectorstore.VectorizedChunks, bool) {
+	chunks := make(vectorstore.VectorizedChunks, 0, len(ids))
+	for _, id := range ids {
+		c, ok := byID[id]
+		if !ok {
+			return nil, false
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, true
+}
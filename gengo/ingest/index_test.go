@@ -0,0 +1,193 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// countingEmbedder embeds texts as small vectors, counting the texts it is
+// asked for and failing those containing poison while it is set.
+type countingEmbedder struct {
+	mu     sync.Mutex
+	texts  int
+	poison string
+}
+
+// embedder returns a Pipeline around the counting embedder that tries
+// each text once, on its own, so a poisoned text fails alone.
+func (c *countingEmbedder) embedder() embedding.Embedder {
+	return &embedding.Pipeline{
+		Embedder: embedding.EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			vectors := [][]float64{}
+			for _, text := range texts {
+				if c.poison != "" && strings.Contains(text, c.poison) {
+					return nil, errors.New("poisoned text")
+				}
+				c.texts++
+				vectors = append(vectors, []float64{float64(len(text)), float64(strings.Count(text, " ")), 1})
+			}
+			return vectors, nil
+		}),
+		BatchSize: 1,
+		Attempts:  1,
+	}
+}
+
+// embedded returns the number of texts embedded so far and resets it.
+func (c *countingEmbedder) embedded() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.texts
+	c.texts = 0
+	return n
+}
+
+// testDoc is a Markdown document with a few sections.
+func testDoc(source string, topic string) document.Document {
+	var b strings.Builder
+	for _, section := range []string{"Install", "Configure", "Run"} {
+		b.WriteString("# " + section + " " + topic + "\n\n")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(&b, "Step %d to %s %s is to follow the instructions carefully and check the result before going on.\n\n", i+1, strings.ToLower(section), topic)
+		}
+	}
+	return document.Document{Source: source, Title: topic, Format: document.FormatMarkdown, Content: b.String()}
+}
+
+// sources returns the sources of the chunks in an index.
+func sources(t *testing.T, path string) map[string]int {
+	t.Helper()
+	chunks, err := vectorstore.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]int{}
+	for _, c := range chunks {
+		if c.Vector == nil {
+			t.Errorf("chunk %s has no vector", c.ID)
+		}
+		out[c.Source]++
+	}
+	return out
+}
+
+func TestIndexerUpdate(t *testing.T) {
+	ctx := context.Background()
+	counter := &countingEmbedder{}
+	ix := Indexer{
+		Path:     filepath.Join(t.TempDir(), "chunks.json"),
+		Split:    split.Config{Size: 40, Overlap: 5},
+		Embedder: counter.embedder(),
+	}
+	a, b := testDoc("a.md", "gengo"), testDoc("b.md", "gopls")
+
+	for _, tc := range []struct {
+		name     string
+		docs     []document.Document
+		want     IndexStats
+		embedded bool
+	}{
+		{"first", []document.Document{a, b}, IndexStats{Documents: 2, Added: 2}, true},
+		{"unchanged", []document.Document{a, b}, IndexStats{Documents: 2, Unchanged: 2}, false},
+		{"changed", []document.Document{a, {Source: b.Source, Title: b.Title, Format: b.Format, Content: b.Content + "# Upgrade gopls\n\nUpgrading gopls needs a newer toolchain.\n"}}, IndexStats{Documents: 2, Changed: 1, Unchanged: 1}, true},
+		{"removed", []document.Document{a}, IndexStats{Documents: 1, Unchanged: 1, Removed: 1}, false},
+	} {
+		stats, err := ix.Update(ctx, tc.docs)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		embedded := counter.embedded()
+		if stats.Embedded != embedded {
+			t.Errorf("%s: stats report %d embedded, the embedder saw %d", tc.name, stats.Embedded, embedded)
+		}
+		if tc.embedded != (embedded > 0) {
+			t.Errorf("%s: embedded %d texts", tc.name, embedded)
+		}
+		got := *stats
+		got.Chunks, got.Embedded = 0, 0
+		if got != tc.want {
+			t.Errorf("%s: got stats %+v, want %+v", tc.name, got, tc.want)
+		}
+
+		// The index holds the chunks of exactly the documents given, as
+		// does the manifest.
+		indexed := sources(t, ix.Path)
+		manifest, err := LoadManifest(ManifestPath(ix.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(indexed) != len(tc.docs) || len(manifest.Documents) != len(tc.docs) {
+			t.Errorf("%s: index has %v and manifest %d documents, want %d", tc.name, indexed, len(manifest.Documents), len(tc.docs))
+		}
+		total := 0
+		for _, d := range tc.docs {
+			if indexed[d.Source] == 0 || len(manifest.Documents[d.Source].Chunks) != indexed[d.Source] {
+				t.Errorf("%s: %s has %d chunks indexed and %d in the manifest", tc.name, d.Source, indexed[d.Source], len(manifest.Documents[d.Source].Chunks))
+			}
+			total += indexed[d.Source]
+		}
+		if stats.Chunks != total {
+			t.Errorf("%s: stats report %d chunks, the index has %d", tc.name, stats.Chunks, total)
+		}
+	}
+}
+
+func TestIndexerPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	counter := &countingEmbedder{poison: "gopls"}
+	ix := Indexer{
+		Path:     filepath.Join(t.TempDir(), "chunks.json"),
+		Split:    split.Config{Size: 40, Overlap: 5},
+		Embedder: counter.embedder(),
+	}
+	docs := []document.Document{testDoc("a.md", "gengo"), testDoc("b.md", "gopls")}
+
+	// The chunks of b cannot be embedded, so b is left out and the rest
+	// is saved.
+	stats, err := ix.Update(ctx, docs)
+	var partial *embedding.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("got error %v, want a *embedding.PartialError", err)
+	}
+	if stats == nil || stats.Failed == 0 {
+		t.Fatalf("got stats %+v, want failed chunks", stats)
+	}
+	indexed := sources(t, ix.Path)
+	if indexed["a.md"] == 0 || indexed["b.md"] != 0 {
+		t.Errorf("index has %v, want only a.md", indexed)
+	}
+	manifest, err := LoadManifest(ManifestPath(ix.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manifest.Documents["b.md"]; ok {
+		t.Error("the manifest lists b.md, which failed")
+	}
+	embeddedA := counter.embedded()
+
+	// Once the embedder recovers, only b is embedded.
+	counter.poison = ""
+	stats, err = ix.Update(ctx, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Unchanged != 1 || stats.Added != 1 {
+		t.Errorf("got stats %+v, want a.md unchanged and b.md added", stats)
+	}
+	indexed = sources(t, ix.Path)
+	if embedded := counter.embedded(); embedded != indexed["b.md"] {
+		t.Errorf("embedded %d texts, want the %d chunks of b.md (a.md took %d)", embedded, indexed["b.md"], embeddedA)
+	}
+}
@@ -0,0 +1,163 @@
+// Package ingest downloads content, splits it into chunks and embeds the
+// chunks for retrieval.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/extract"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// Default chunking parameters, in whitespace separated tokens.
+const (
+	DefaultChunkSize = 100
+	DefaultOverlap   = 10
+)
+
+// CharacterTextSplitter takes in a string and splits the string into
+// chunks of a given size (split on whitespace) with an overlap of a
+// given size of tokens (split on whitespace).
+func CharacterTextSplitter(text string, splitSize int, overlapSize int) []string {
+
+	// Create a slice to hold the chunks.
+	chunks := []string{}
+
+	// Split the text into tokens based on whitespace.
+	tokens := strings.Split(text, " ")
+
+	// Loop over the tokens creating chunks of size splitSize, each
+	// starting overlapSize tokens before the end of the last.
+	step := max(splitSize-overlapSize, 1)
+	for i := 0; i < len(tokens); i += step {
+		end := min(i+splitSize, len(tokens))
+		chunks = append(chunks, strings.Join(tokens[i:end], " "))
+		if end == len(tokens) {
+			break
+		}
+	}
+	return chunks
+}
+
+// WebsiteMarkdown downloads a website and converts its main content to
+// markdown with an optional start string and end string. A start or end
+// string that is not in the page is an error matching extract.ErrNotFound.
+func WebsiteMarkdown(ctx context.Context, website string, start string, end string) (string, error) {
+	content, err := WebsiteContent(ctx, website, extract.New(extract.Rule{Start: start, End: end}))
+	if err != nil {
+		return "", err
+	}
+	return content.Markdown, nil
+}
+
+// WebsiteContent downloads a website and extracts its main content as
+// markdown with the extractor's rules.
+func WebsiteContent(ctx context.Context, website string, e *extract.Extractor) (*extract.Content, error) {
+
+	// Download the website.
+	req, err := http.NewRequestWithContext(ctx, "GET", website, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ingest: %s: %s", website, res.Status)
+	}
+
+	// Extract the main content as markdown for convenience.
+	return e.ExtractString(res.Request.URL.String(), string(content))
+}
+
+// WebsiteChunks loads in a website and splits it into chunks with an
+// optional start string and end string.
+func WebsiteChunks(ctx context.Context, website string, start string, end string) ([]string, error) {
+	markdown, err := WebsiteMarkdown(ctx, website, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Split the text into reasonable size chunks with an overlap.
+	chunks := CharacterTextSplitter(markdown, DefaultChunkSize, DefaultOverlap)
+	return chunks, nil
+}
+
+// DocumentChunks splits every document into chunks with the splitter, or
+// a Markdown splitter if it is nil, and describes each chunk with its
+// document's source, title and metadata. Go declarations are already
+// chunks and are kept whole.
+func DocumentChunks(ctx context.Context, splitter split.Splitter, docs []document.Document) ([]document.Chunk, error) {
+	if splitter == nil {
+		splitter = split.NewMarkdown()
+	}
+	ingested := time.Now().UTC()
+	chunks := []document.Chunk{}
+	for _, d := range docs {
+		docChunks := []document.Chunk{{Content: d.Content, End: len(d.Content)}}
+		if d.Format != document.FormatGo || d.Metadata["declaration"] == "" {
+			var err error
+			if docChunks, err = splitter.Split(ctx, d); err != nil {
+				return nil, err
+			}
+		}
+		for _, c := range docChunks {
+			c.Ingested = ingested
+			chunks = append(chunks, d.Describe(c))
+		}
+	}
+	return chunks, nil
+}
+
+// Embed vectorizes the chunks in concurrent batches with e, or with a
+// default embedding.Pipeline around it if it is not one. If some batches
+// fail, the chunks that were embedded are returned along with the
+// *embedding.PartialError.
+func Embed(ctx context.Context, e embedding.Embedder, chunks []document.Chunk) (vectorstore.VectorizedChunks, error) {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Content
+	}
+	vectors, err := pipeline(e).Embed(ctx, texts)
+	var partial *embedding.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+
+	// Pair each chunk with its vector.
+	vectorizedChunks := make(vectorstore.VectorizedChunks, 0, len(chunks))
+	for i, chunk := range chunks {
+		if vectors[i] == nil {
+			continue
+		}
+		vectorizedChunks = append(vectorizedChunks, vectorstore.VectorizedChunk{
+			Chunk:  chunk,
+			Vector: vectors[i],
+		})
+	}
+	return vectorizedChunks, err
+}
+
+// pipeline returns e if it is a Pipeline, and otherwise a Pipeline with
+// the default settings around it.
+func pipeline(e embedding.Embedder) *embedding.Pipeline {
+	if p, ok := e.(*embedding.Pipeline); ok {
+		return p
+	}
+	return embedding.NewPipeline(e)
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Guard checks that can be requested on TypedOutput.
+const (
+	CheckToxicity    = "toxicity"
+	CheckFactuality  = "factuality"
+	CheckConsistency = "consistency"
+)
+
+// Sentinel errors for failed guard checks. A *GuardError matches ErrGuard
+// and the error of its check with errors.Is.
+var (
+	ErrGuard       = errors.New("llm: completion failed a guard check")
+	ErrToxicity    = errors.New("llm: completion failed the toxicity check")
+	ErrFactuality  = errors.New("llm: completion failed the factuality check")
+	ErrConsistency = errors.New("llm: completion failed the consistency check")
+)
+
+// checkErrors maps each check to its sentinel error.
+var checkErrors = map[string]error{
+	CheckToxicity:    ErrToxicity,
+	CheckFactuality:  ErrFactuality,
+	CheckConsistency: ErrConsistency,
+}
+
+// GuardResult is the outcome of the guard checks on a completion.
+type GuardResult struct {
+	Passed bool `json:"passed"`
+
+	// Check names the check that failed, or is empty if the failure could
+	// not be attributed to one.
+	Check string `json:"check,omitempty"`
+
+	// Score and Threshold are set when the API reports them. A check
+	// fails when its score crosses the threshold.
+	Score     float64 `json:"score,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// Message is the status the API returned.
+	Message string `json:"message,omitempty"`
+}
+
+// Err returns nil if the checks passed and a *GuardError otherwise.
+func (r *GuardResult) Err() error {
+	if r == nil || r.Passed {
+		return nil
+	}
+	return &GuardError{Result: *r}
+}
+
+// GuardError is returned when a completion fails a guard check. The
+// response is kept so callers can decide whether to retry, regenerate
+// with different parameters or redact the text.
+type GuardError struct {
+	Result   GuardResult
+	Response *CompletionResults
+}
+
+// Error implements the error interface.
+func (e *GuardError) Error() string {
+	check := e.Result.Check
+	if check == "" {
+		check = "guard"
+	}
+	msg := fmt.Sprintf("llm: completion failed the %s check", check)
+	if e.Result.Threshold != 0 {
+		msg += fmt.Sprintf(" (score %g, threshold %g)", e.Result.Score, e.Result.Threshold)
+	}
+	if e.Result.Message != "" {
+		msg += ": " + e.Result.Message
+	}
+	return msg
+}
+
+// Is reports whether target is ErrGuard or the sentinel of the failed
+// check.
+func (e *GuardError) Is(target error) bool {
+	return target == ErrGuard || target == checkErrors[e.Result.Check]
+}
+
+// scorePattern and thresholdPattern find numbers reported in a status.
+var (
+	scorePattern     = regexp.MustCompile(`(?i)score\W+([0-9]*\.?[0-9]+)`)
+	thresholdPattern = regexp.MustCompile(`(?i)threshold\W+([0-9]*\.?[0-9]+)`)
+)
+
+// ParseGuardStatus reads the status of a completion, like "success" or
+// "error: failed a toxicity check", into a GuardResult.
+func ParseGuardStatus(status string) *GuardResult {
+	status = strings.TrimSpace(status)
+	if status == "" || strings.EqualFold(status, "success") {
+		return &GuardResult{Passed: true, Message: status}
+	}
+
+	result := GuardResult{Message: status}
+	lower := strings.ToLower(status)
+	switch {
+	case strings.Contains(lower, "toxic"):
+		result.Check = CheckToxicity
+	case strings.Contains(lower, "factual"):
+		result.Check = CheckFactuality
+	case strings.Contains(lower, "consisten"):
+		result.Check = CheckConsistency
+	}
+	if m := scorePattern.FindStringSubmatch(status); m != nil {
+		result.Score, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if m := thresholdPattern.FindStringSubmatch(status); m != nil {
+		result.Threshold, _ = strconv.ParseFloat(m[1], 64)
+	}
+	return &result
+}
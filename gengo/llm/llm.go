@@ -0,0 +1,224 @@
+// Package llm is a client for the Prediction Guard completions API.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultURL is the Prediction Guard completions endpoint.
+const DefaultURL = "https://api.predictionguard.com/completions"
+
+// DefaultModel is the model used when a request does not name one.
+const DefaultModel = "Nous-Hermes-Llama2-13B"
+
+// CompletionResult is a single completion result.
+type CompletionResult struct {
+	Text   string      `json:"text"`
+	Output interface{} `json:"output,omitempty"`
+	Index  int         `json:"index"`
+	Status string      `json:"status"`
+	Model  string      `json:"model"`
+
+	// Guard is the outcome of the guard checks, parsed from Status by
+	// the client.
+	Guard *GuardResult `json:"-"`
+
+	// Value is the Text parsed according to the requested output type,
+	// see ParseOutput for the Go type of each. It is checked and set by
+	// the client, not the API, and is nil when the request did not ask
+	// for a typed output.
+	Value any `json:"-"`
+}
+
+// CompletionResults is a list of completion results.
+type CompletionResults struct {
+	Id      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Choices []CompletionResult `json:"choices"`
+}
+
+// TypedOutput is a struct type that represents a typed output.
+type TypedOutput struct {
+	Type        string   `json:"type"`
+	Categories  []string `json:"categories"`
+	Pattern     string   `json:"pattern"`
+	Consistency bool     `json:"consistency"`
+	Factuality  bool     `json:"factuality"`
+	Toxicity    bool     `json:"toxicity"`
+
+	// Fallback is the value used when the completion does not match the
+	// output type. It is parsed like a completion, except for categorical
+	// and regex outputs where it is used as is. If it is empty such a
+	// completion is an *OutputError.
+	Fallback string `json:"-"`
+
+	// Min and Max, if set, bound integer and float outputs.
+	Min *float64 `json:"-"`
+	Max *float64 `json:"-"`
+
+	// MinItems and MaxItems, if not zero, bound the length of list
+	// outputs.
+	MinItems int `json:"-"`
+	MaxItems int `json:"-"`
+}
+
+// CompletionRequest is a struct type that represents a completion request.
+type CompletionRequest struct {
+	Model       string      `json:"model"`
+	Prompt      string      `json:"prompt"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature float64     `json:"temperature"`
+	TopP        float64     `json:"top_p,omitempty"`
+	Output      TypedOutput `json:"output"`
+
+	// Stop holds the stop tokens the typed output is parsed up to, see
+	// TrimStop. The Text of the results is left as the model returned it.
+	Stop []string `json:"-"`
+}
+
+// Completer gets text completions for a request.
+type Completer interface {
+	Complete(ctx context.Context, request CompletionRequest) (*CompletionResults, error)
+}
+
+// ErrNoChoices is returned when the API responds without any completions.
+var ErrNoChoices = errors.New("llm: response contained no choices")
+
+// APIError is returned when the API responds with a non-200 status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm: api returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the Prediction Guard API to get text completions.
+type Client struct {
+	URL        string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the default endpoint, or the one named by
+// the PREDICTIONGUARD_URL environment variable. If token is empty the
+// PREDICTIONGUARD_TOKEN environment variable is used.
+func NewClient(token string) *Client {
+	if token == "" {
+		token = os.Getenv("PREDICTIONGUARD_TOKEN")
+	}
+	url := os.Getenv("PREDICTIONGUARD_URL")
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{
+		URL:        url,
+		Token:      token,
+		HTTPClient: &http.Client{},
+	}
+}
+
+// Complete calls the Prediction Guard API to get text completions.
+func (c *Client) Complete(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+	if request.Model == "" {
+		request.Model = DefaultModel
+	}
+
+	// Prepare the payload.
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the POST request.
+	url := c.URL
+	if url == "" {
+		url = DefaultURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+c.Token)
+	req.Header.Add("Content-Type", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Surface API failures with whatever message the server gave us.
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, &APIError{StatusCode: res.StatusCode, Message: errorMessage(body)}
+	}
+
+	// Read the response body into a CompletionResults value.
+	var results CompletionResults
+	if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	if len(results.Choices) == 0 {
+		return nil, ErrNoChoices
+	}
+
+	// Surface failed guard checks as errors, keeping the response so the
+	// caller can decide what to do with it.
+	for i := range results.Choices {
+		choice := &results.Choices[i]
+		choice.Guard = ParseGuardStatus(choice.Status)
+		if !choice.Guard.Passed {
+			return nil, &GuardError{Result: *choice.Guard, Response: &results}
+		}
+	}
+
+	// Check the typed output locally rather than trusting the server to
+	// have honored it, ignoring anything after a stop token.
+	if request.Output.Type != "" {
+		for i := range results.Choices {
+			choice := &results.Choices[i]
+			if choice.Value, err = ParseOutput(request.Output, TrimStop(choice.Text, request.Stop)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &results, nil
+}
+
+// errorMessage extracts the error message from an API error body.
+func errorMessage(body []byte) string {
+	var e struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err == nil && e.Error != "" {
+		return e.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// TrimStop post processes a completion. Models might return some "extra"
+// stuff after the input/output indicators, so we truncate the completion
+// on the first stop token encountered and trim the whitespace.
+func TrimStop(completion string, stop []string) string {
+	for _, s := range stop {
+		if strings.Contains(completion, s) {
+			completion = completion[:strings.Index(completion, s)]
+		}
+	}
+	return strings.TrimSpace(completion)
+}
@@ -0,0 +1,258 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Middleware adds behavior around a Completer, like guards, caching,
+// retries or logging.
+type Middleware func(next Completer) Completer
+
+// CompleterFunc adapts a function to a Completer.
+type CompleterFunc func(ctx context.Context, request CompletionRequest) (*CompletionResults, error)
+
+// Complete implements Completer.
+func (f CompleterFunc) Complete(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+	return f(ctx, request)
+}
+
+// Chain wraps c in the middleware. The first middleware is the outermost:
+// it sees the request first and the response last, so
+//
+//	llm.Chain(client, redact, cache, retry, trace)
+//
+// redacts prompts before they are cached, and only retries and traces
+// requests that missed the cache.
+func Chain(c Completer, middleware ...Middleware) Completer {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		c = middleware[i](c)
+	}
+	return c
+}
+
+// RewritePrompt returns middleware that rewrites every prompt before it is
+// sent.
+func RewritePrompt(rewrite func(prompt string) string) Middleware {
+	return func(next Completer) Completer {
+		return CompleterFunc(func(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+			request.Prompt = rewrite(request.Prompt)
+			return next.Complete(ctx, request)
+		})
+	}
+}
+
+// Logging returns middleware that logs the model, latency and outcome of
+// every request.
+func Logging(logger *log.Logger) Middleware {
+	return func(next Completer) Completer {
+		return CompleterFunc(func(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+			start := time.Now()
+			response, err := next.Complete(ctx, request)
+			latency := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				logger.Printf("complete model=%s prompt_chars=%d latency=%s error=%q", request.Model, len(request.Prompt), latency, err)
+				return nil, err
+			}
+			logger.Printf("complete model=%s prompt_chars=%d latency=%s choices=%d", request.Model, len(request.Prompt), latency, len(response.Choices))
+			return response, nil
+		})
+	}
+}
+
+// Retry returns middleware that retries requests that failed for reasons
+// that may pass, like network errors, rate limits and server errors, up
+// to attempts times in all. The wait between attempts starts at backoff
+// and doubles each time.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return func(next Completer) Completer {
+		return CompleterFunc(func(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+			wait := backoff
+			for attempt := 1; ; attempt++ {
+				response, err := next.Complete(ctx, request)
+				if err == nil || attempt >= attempts || !Temporary(err) {
+					return response, err
+				}
+
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C:
+				}
+				wait *= 2
+			}
+		})
+	}
+}
+
+// Temporary reports whether a request that failed with err might succeed
+// if retried: the API rate limited it or failed with a server error, or
+// the network timed out. Every other error is final, including guard
+// failures, output mismatches, cancellations and malformed responses.
+func Temporary(err error) bool {
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &apiErr):
+		return TemporaryStatus(apiErr.StatusCode)
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// TemporaryStatus reports whether a request answered with an HTTP status
+// might succeed if retried, which is the case for rate limiting and server
+// errors.
+func TemporaryStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// Cache is an in-memory, least recently used cache of completions.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// cacheEntry is a cached completion.
+type cacheEntry struct {
+	key      string
+	response *CompletionResults
+}
+
+// NewCache returns a cache that holds up to size completions.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Middleware returns middleware that answers repeated requests from the
+// cache. Only requests with a temperature of zero are cached, since
+// sampled requests are expected to differ.
+func (c *Cache) Middleware(next Completer) Completer {
+	return CompleterFunc(func(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+		if request.Temperature != 0 {
+			return next.Complete(ctx, request)
+		}
+		key, err := cacheKey(request)
+		if err != nil {
+			return next.Complete(ctx, request)
+		}
+		if response, ok := c.get(key); ok {
+			return response, nil
+		}
+
+		response, err := next.Complete(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, response)
+		return clone(response), nil
+	})
+}
+
+// get returns a copy of the cached response for key.
+func (c *Cache) get(key string) (*CompletionResults, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return clone(e.Value.(*cacheEntry).response), true
+}
+
+// put stores a copy of the response, evicting the least recently used
+// entry if the cache is full.
+func (c *Cache) put(key string, response *CompletionResults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*cacheEntry).response = clone(response)
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, response: clone(response)})
+	for c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey identifies a request, including the output settings that are
+// checked on the client and not sent to the API.
+func cacheKey(request CompletionRequest) (string, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	o := request.Output
+	local := fmt.Sprintf("%q %q %q %q %v %v %d %d %q", o.Type, o.Categories, o.Pattern, o.Fallback, deref(o.Min), deref(o.Max), o.MinItems, o.MaxItems, request.Stop)
+	return string(payload) + "\x00" + local, nil
+}
+
+// deref returns the value of p, or nil.
+func deref(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// clone copies a response so callers can modify it without changing the
+// cached one, down to the lists and objects of typed values.
+func clone(response *CompletionResults) *CompletionResults {
+	out := *response
+	out.Choices = append([]CompletionResult(nil), response.Choices...)
+	for i := range out.Choices {
+		choice := &out.Choices[i]
+		choice.Output = cloneValue(choice.Output)
+		choice.Value = cloneValue(choice.Value)
+		if choice.Guard != nil {
+			guard := *choice.Guard
+			choice.Guard = &guard
+		}
+	}
+	return &out
+}
+
+// cloneValue deep copies the slices and maps of a parsed value. Other
+// values, like strings, numbers and times, are copied by assignment.
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case []string:
+		return append([]string(nil), v...)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = cloneValue(item)
+		}
+		return out
+	}
+	return value
+}
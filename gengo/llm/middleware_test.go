@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+// timeoutError is a network error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestTemporary(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	_, badURL := url.Parse("://no-scheme")
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &APIError{StatusCode: 429}, true},
+		{"server error", &APIError{StatusCode: 503}, true},
+		{"wrapped server error", fmt.Errorf("chat: %w", &APIError{StatusCode: 500}), true},
+		{"unauthorized", &APIError{StatusCode: 401}, false},
+		{"bad request", &APIError{StatusCode: 400}, false},
+		{"network timeout", &url.Error{Op: "Post", URL: "https://example.com", Err: timeoutError{}}, true},
+		{"connection refused", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, false},
+		{"cancelled", context.Canceled, false},
+		{"deadline", &url.Error{Op: "Post", URL: "https://example.com", Err: context.DeadlineExceeded}, false},
+		{"guard", &GuardError{Result: GuardResult{Check: CheckToxicity}}, false},
+		{"output", &OutputError{Type: OutputInteger, Text: "many"}, false},
+		{"decode", json.Unmarshal([]byte("{"), &syntaxErr), false},
+		{"bad url", badURL, false},
+		{"no choices", ErrNoChoices, false},
+		{"unknown", errors.New("something else"), false},
+	} {
+		if got := Temporary(tc.err); got != tc.want {
+			t.Errorf("%s: Temporary(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"temporary", &APIError{StatusCode: 502}, 3},
+		{"final", &APIError{StatusCode: 400}, 1},
+		{"unknown", errors.New("bad response"), 1},
+	} {
+		calls := 0
+		c := Retry(3, 0)(CompleterFunc(func(ctx context.Context, request CompletionRequest) (*CompletionResults, error) {
+			calls++
+			return nil, tc.err
+		}))
+		if _, err := c.Complete(context.Background(), CompletionRequest{}); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+		if calls != tc.calls {
+			t.Errorf("%s: tried %d times, want %d", tc.name, calls, tc.calls)
+		}
+	}
+}
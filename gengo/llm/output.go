@@ -0,0 +1,505 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Output types checked by ParseOutput.
+const (
+	OutputCategorical = "categorical"
+	OutputRegex       = "regex"
+	OutputInteger     = "integer"
+	OutputFloat       = "float"
+	OutputBoolean     = "boolean"
+	OutputDate        = "date"
+	OutputList        = "list"
+	OutputJSON        = "json"
+)
+
+// apiOutputTypes are the output types the API itself defines. The others
+// are checked by ParseOutput alone and not sent.
+var apiOutputTypes = map[string]bool{
+	OutputCategorical: true,
+}
+
+// MarshalJSON encodes the output for the API, leaving out the type and its
+// settings when the API does not define it.
+func (o TypedOutput) MarshalJSON() ([]byte, error) {
+	type wire TypedOutput
+	w := wire(o)
+	if !apiOutputTypes[o.Type] {
+		w.Type, w.Categories, w.Pattern = "", nil, ""
+	}
+	return json.Marshal(w)
+}
+
+// OutputError is returned when a completion does not match the requested
+// output type and there is no fallback.
+type OutputError struct {
+	Type   string
+	Text   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("llm: %s output %q %s", e.Type, e.Text, e.Reason)
+}
+
+// ErrOutputType is returned for an output type ParseOutput does not know.
+var ErrOutputType = errors.New("llm: unknown output type")
+
+// CheckOutputType returns an error matching ErrOutputType if t is neither
+// empty nor one of the Output types.
+func CheckOutputType(t string) error {
+	if _, ok := parsers[t]; !ok && t != "" {
+		return fmt.Errorf("%w %q", ErrOutputType, t)
+	}
+	return nil
+}
+
+// parser parses a completion into a value of an output type, or says why
+// it could not.
+type parser func(output TypedOutput, text string) (value any, reason string)
+
+// parsers holds the parser of each output type.
+var parsers = map[string]parser{
+	OutputCategorical: parseCategorical,
+	OutputRegex:       parseRegex,
+	OutputInteger:     parseInteger,
+	OutputFloat:       parseFloat,
+	OutputBoolean:     parseBoolean,
+	OutputDate:        parseDate,
+	OutputList:        parseList,
+	OutputJSON:        parseJSON,
+}
+
+// ParseOutput checks a completion against the requested output type and
+// returns its value:
+//
+//	categorical  string          the matching category, as written in Categories
+//	regex        string          the first match of Pattern in the text
+//	integer      int64           the first whole number, within Min and Max
+//	float        float64         the first number, within Min and Max
+//	boolean      bool            true/yes or false/no
+//	date         time.Time       the first ISO 8601 date or date-time
+//	list         []string        one item per line or comma, bulleted or not,
+//	                             or a JSON array, with MinItems to MaxItems
+//	                             items that are all Categories if given
+//	json         map[string]any  the first JSON object
+//
+// Models wrap answers in prose, so values are found within the text rather
+// than required to be all of it, and small differences in casing,
+// punctuation and spelling are forgiven for categories, so "Yes." matches
+// "yes". If nothing matches, the Fallback is returned, or an *OutputError
+// when there is none. Without a type the trimmed text is returned, and an
+// unknown type is an error matching ErrOutputType, so a misspelled type
+// does not turn the check off.
+func ParseOutput(output TypedOutput, text string) (any, error) {
+	if output.Type == "" {
+		return strings.TrimSpace(text), nil
+	}
+	if err := CheckOutputType(output.Type); err != nil {
+		return nil, err
+	}
+	parse := parsers[output.Type]
+	if output.Type == OutputRegex {
+		if _, err := regexp.Compile(output.Pattern); err != nil {
+			return nil, fmt.Errorf("llm: output pattern: %w", err)
+		}
+	}
+
+	value, reason := parse(output, text)
+	if reason == "" {
+		return value, nil
+	}
+	if output.Fallback == "" {
+		return nil, &OutputError{Type: output.Type, Text: text, Reason: reason}
+	}
+
+	// Categories and patterns are strings, so the fallback can be any
+	// string, like "unknown". Other fallbacks must parse.
+	if output.Type == OutputCategorical || output.Type == OutputRegex {
+		return output.Fallback, nil
+	}
+	value, reason = parse(output, output.Fallback)
+	if reason != "" {
+		return nil, fmt.Errorf("llm: fallback %q %s", output.Fallback, reason)
+	}
+	return value, nil
+}
+
+// parseCategorical matches the text to a category.
+func parseCategorical(output TypedOutput, text string) (any, string) {
+	if category, ok := MatchCategory(output.Categories, text); ok {
+		return category, ""
+	}
+	return nil, "is not one of " + strings.Join(output.Categories, ", ")
+}
+
+// parseRegex finds the first match of the pattern.
+func parseRegex(output TypedOutput, text string) (any, string) {
+	re := regexp.MustCompile(output.Pattern)
+	text = strings.TrimSpace(text)
+	if loc := re.FindStringIndex(text); loc != nil {
+		return text[loc[0]:loc[1]], ""
+	}
+	return nil, "does not match " + output.Pattern
+}
+
+// numberPattern matches integers and decimals, with optional thousands
+// separators and exponent.
+var numberPattern = regexp.MustCompile(`[-+]?(\d{1,3}(,\d{3})+|\d+)?(\.\d+)?([eE][-+]?\d+)?`)
+
+// findNumber returns the first number in text.
+func findNumber(text string) (float64, bool) {
+	for _, match := range numberPattern.FindAllString(text, -1) {
+		if strings.IndexFunc(match, unicode.IsDigit) < 0 {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+		if err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// inRange checks n against the bounds of the output, returning why it is
+// out of range.
+func inRange(output TypedOutput, n float64) string {
+	switch {
+	case output.Min != nil && n < *output.Min:
+		return fmt.Sprintf("is less than %g", *output.Min)
+	case output.Max != nil && n > *output.Max:
+		return fmt.Sprintf("is greater than %g", *output.Max)
+	}
+	return ""
+}
+
+// parseInteger finds the first whole number.
+func parseInteger(output TypedOutput, text string) (any, string) {
+	n, ok := findNumber(text)
+	if !ok {
+		return nil, "does not contain a number"
+	}
+	if n != math.Trunc(n) || math.Abs(n) > math.MaxInt64 {
+		return nil, "is not a whole number"
+	}
+	if reason := inRange(output, n); reason != "" {
+		return nil, reason
+	}
+	return int64(n), ""
+}
+
+// parseFloat finds the first number.
+func parseFloat(output TypedOutput, text string) (any, string) {
+	n, ok := findNumber(text)
+	if !ok {
+		return nil, "does not contain a number"
+	}
+	if reason := inRange(output, n); reason != "" {
+		return nil, reason
+	}
+	return n, ""
+}
+
+// booleans maps the words accepted for each boolean value.
+var booleans = map[string]bool{
+	"true":  true,
+	"yes":   true,
+	"false": false,
+	"no":    false,
+}
+
+// parseBoolean reads true/yes or false/no.
+func parseBoolean(output TypedOutput, text string) (any, string) {
+	word, ok := MatchCategory([]string{"true", "yes", "false", "no"}, text)
+	if !ok {
+		return nil, "is not true or false"
+	}
+	return booleans[word], ""
+}
+
+// datePattern matches ISO 8601 dates with an optional time and offset.
+var datePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[-+]\d{2}:\d{2})?)?`)
+
+// dateLayouts are the layouts tried for a date match, longest first.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseDate finds the first ISO 8601 date.
+func parseDate(output TypedOutput, text string) (any, string) {
+	match := datePattern.FindString(text)
+	if match == "" {
+		return nil, "does not contain an ISO 8601 date"
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, match); err == nil {
+			return t, ""
+		}
+	}
+	return nil, fmt.Sprintf("contains %s which is not a valid date", match)
+}
+
+// bullet matches list markers at the start of an item: "-", "*", "1." or
+// "1)".
+var bullet = regexp.MustCompile(`^([-*•]|\d+[.)])\s+`)
+
+// parseList splits the text into items.
+func parseList(output TypedOutput, text string) (any, string) {
+	items := []string{}
+	if raw, err := ExtractJSON(text); err == nil && strings.HasPrefix(string(raw), "[") {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, "is not a JSON array of strings"
+		}
+	} else {
+
+		// One item per line, or comma separated if it is all on one line.
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		if len(lines) == 1 {
+			lines = strings.Split(lines[0], ",")
+		}
+		for _, line := range lines {
+			item := bullet.ReplaceAllString(strings.TrimSpace(line), "")
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	if len(output.Categories) > 0 {
+		for i, item := range items {
+			category, ok := MatchCategory(output.Categories, item)
+			if !ok {
+				return nil, fmt.Sprintf("has item %q which is not one of %s", item, strings.Join(output.Categories, ", "))
+			}
+			items[i] = category
+		}
+	}
+
+	switch {
+	case len(items) < output.MinItems:
+		return nil, fmt.Sprintf("has %d items, fewer than %d", len(items), output.MinItems)
+	case output.MaxItems > 0 && len(items) > output.MaxItems:
+		return nil, fmt.Sprintf("has %d items, more than %d", len(items), output.MaxItems)
+	}
+	return items, ""
+}
+
+// parseJSON finds the first JSON object.
+func parseJSON(output TypedOutput, text string) (any, string) {
+	raw, err := ExtractJSON(text)
+	if err != nil {
+		return nil, err.Error()
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, "is not a JSON object"
+	}
+	return obj, ""
+}
+
+// ExtractJSON returns the first JSON object or array in text, skipping any
+// prose or code fences the model put around it.
+func ExtractJSON(text string) ([]byte, error) {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return nil, errors.New("no JSON object or array found")
+	}
+
+	// Let the decoder find where the value ends.
+	dec := json.NewDecoder(strings.NewReader(text[start:]))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return raw, nil
+}
+
+// MatchCategory finds the category a completion refers to. It tries, in
+// order: an exact match ignoring case and surrounding punctuation, a
+// category the text starts with, the last category mentioned in the text
+// and not negated, the only category within a small edit distance, and
+// the last abbreviation, a category of three or more capitals, that a
+// word starts with, so "neutral" matches "NEU".
+func MatchCategory(categories []string, text string) (string, bool) {
+	norm := normalize(text)
+	if norm == "" {
+		return "", false
+	}
+
+	for _, c := range categories {
+		if normalize(c) == norm {
+			return c, true
+		}
+	}
+
+	// "Yes, the user is asking..." starts with a category. Prefer the
+	// longest so "not sure" wins over "not".
+	best := ""
+	for _, c := range categories {
+		nc := normalize(c)
+		if nc != "" && hasWordPrefix(norm, nc) && len(nc) > len(normalize(best)) {
+			best = c
+		}
+	}
+	if best != "" {
+		return best, true
+	}
+
+	// "I would say this is a purchase" mentions a category, and "it is
+	// not POS, it is NEG" negates all but one. Models end on their answer,
+	// so of several the last is taken.
+	if c, ok := lastMention(categories, norm, false); ok {
+		return c, true
+	}
+
+	// "purchace" is a typo of a single category.
+	closest, closestDist, ties := "", -1, 0
+	for _, c := range categories {
+		nc := normalize(c)
+		d := editDistance(norm, nc)
+		if d > max(1, len(nc)/4) {
+			continue
+		}
+		switch {
+		case closestDist < 0 || d < closestDist:
+			closest, closestDist, ties = c, d, 0
+		case d == closestDist:
+			ties++
+		}
+	}
+	if closest != "" && ties == 0 {
+		return closest, true
+	}
+
+	// "Neutral" is a word that the abbreviated category "NEU" starts.
+	return lastMention(categories, norm, true)
+}
+
+// negations are the words that negate the category after them.
+var negations = map[string]bool{"not": true, "never": true, "isn't": true, "isnt": true}
+
+// lastMention returns the category mentioned last in text and not right
+// after a negation, preferring the longer of two that end together, so
+// "very good" wins over "good". If prefix is set, only abbreviations are
+// matched, at the start of any word.
+func lastMention(categories []string, text string, prefix bool) (string, bool) {
+	best, bestEnd, bestLen := "", -1, 0
+	for _, c := range categories {
+		nc := normalize(c)
+		if prefix && !isAbbreviation(c) {
+			continue
+		}
+		for _, start := range wordIndexes(text, nc, prefix) {
+			if negated(text[:start]) {
+				continue
+			}
+			end := start + len(nc)
+			if end > bestEnd || end == bestEnd && len(nc) > bestLen {
+				best, bestEnd, bestLen = c, end, len(nc)
+			}
+		}
+	}
+	return best, best != ""
+}
+
+// isAbbreviation reports whether a category is three or more letters, all
+// capitals, like "NEU".
+func isAbbreviation(category string) bool {
+	return utf8.RuneCountInString(category) >= 3 && strings.IndexFunc(category, func(r rune) bool {
+		return !unicode.IsUpper(r)
+	}) < 0
+}
+
+// negated reports whether the last word of text is a negation.
+func negated(text string) bool {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) && r != '\'' })
+	return len(words) > 0 && negations[words[len(words)-1]]
+}
+
+// normalize lower cases text, collapses whitespace and trims surrounding
+// punctuation and quotes.
+func normalize(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	return strings.TrimFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// hasWordPrefix reports whether text starts with word followed by a word
+// boundary.
+func hasWordPrefix(text, word string) bool {
+	if !strings.HasPrefix(text, word) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(text[len(word):])
+	return len(text) == len(word) || !isWordRune(next)
+}
+
+// wordIndexes returns the offsets at which word appears in text on word
+// boundaries, or if prefix is set, at the start of a word.
+func wordIndexes(text, word string, prefix bool) []int {
+	indexes := []int{}
+	if word == "" {
+		return indexes
+	}
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			break
+		}
+		start, end := offset+i, offset+i+len(word)
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		before := start == 0 || !isWordRune(prev)
+		after := end == len(text) || !isWordRune(next)
+		if before && (after || prefix) {
+			indexes = append(indexes, start)
+		}
+		offset = start + 1
+	}
+	return indexes
+}
+
+// isWordRune reports whether r is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// editDistance is the Levenshtein distance between a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
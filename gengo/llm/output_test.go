@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchCategory(t *testing.T) {
+	yesNo := []string{"yes", "no"}
+	intents := []string{"purchase", "chat", "return", "other"}
+	sentiments := []string{"POS", "NEG", "NEU"}
+	for _, tc := range []struct {
+		categories []string
+		text       string
+		want       string
+	}{
+		{yesNo, "Yes.", "yes"},
+		{yesNo, `"NO"`, "no"},
+		{yesNo, "Yes, the user is asking a question.", "yes"},
+		{intents, "I would say this is a purchase.", "purchase"},
+		{intents, "purchace", "purchase"},
+		{[]string{"good", "very good"}, "This is very good", "very good"},
+		{[]string{"not sure", "not"}, "Not sure, maybe", "not sure"},
+
+		// Negated categories are skipped, and of several the last wins.
+		{sentiments, "It is not POS, it is NEG", "NEG"},
+		{sentiments, "It is never NEG, but NEU", "NEU"},
+		{[]string{"positive", "negative"}, "The review is not positive", ""},
+		{intents, "Not a purchase but a return", "return"},
+
+		// Abbreviations match the words they start, but only for
+		// categories of three or more capitals.
+		{sentiments, "Neutral", "NEU"},
+		{sentiments, "Positive sentiment.", "POS"},
+		{sentiments, "It is not positive, rather negative.", "NEG"},
+		{yesNo, "Nobody knows", ""},
+		{[]string{"chat", "return"}, "Chatting about returns", ""},
+
+		{yesNo, "I don't know", ""},
+		{[]string{"cat", "car"}, "caz", ""},
+		{yesNo, "  ...  ", ""},
+	} {
+		got, ok := MatchCategory(tc.categories, tc.text)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("MatchCategory(%q, %q) = %q, %v, want %q", tc.categories, tc.text, got, ok, tc.want)
+		}
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	ten, zero := 10.0, 0.0
+	for _, tc := range []struct {
+		name   string
+		output TypedOutput
+		text   string
+		want   any
+	}{
+		{"no type", TypedOutput{}, "  Hello.\n", "Hello."},
+		{"categorical", TypedOutput{Type: OutputCategorical, Categories: []string{"yes", "no"}}, "No, it is not.", "no"},
+		{"categorical fallback", TypedOutput{Type: OutputCategorical, Categories: []string{"yes", "no"}, Fallback: "unknown"}, "Perhaps.", "unknown"},
+		{"regex", TypedOutput{Type: OutputRegex, Pattern: `[A-Z]{3}-\d+`}, "The ticket is ABC-123.", "ABC-123"},
+		{"integer", TypedOutput{Type: OutputInteger}, "About 1,234 gophers.", int64(1234)},
+		{"negative integer", TypedOutput{Type: OutputInteger, Min: &zero}, "-3", nil},
+		{"integer out of range", TypedOutput{Type: OutputInteger, Max: &ten}, "42", nil},
+		{"integer fallback", TypedOutput{Type: OutputInteger, Max: &ten, Fallback: "0"}, "42", int64(0)},
+		{"not an integer", TypedOutput{Type: OutputInteger}, "2.5", nil},
+		{"float", TypedOutput{Type: OutputFloat}, "Roughly 3.14, or 1e3.", 3.14},
+		{"no number", TypedOutput{Type: OutputFloat}, "many", nil},
+		{"boolean", TypedOutput{Type: OutputBoolean}, "Yes, it is.", true},
+		{"false", TypedOutput{Type: OutputBoolean}, "FALSE", false},
+		{"date", TypedOutput{Type: OutputDate}, "Released on 2023-09-27.", time.Date(2023, 9, 27, 0, 0, 0, 0, time.UTC)},
+		{"date-time", TypedOutput{Type: OutputDate}, "At 2023-09-27T10:30:00Z.", time.Date(2023, 9, 27, 10, 30, 0, 0, time.UTC)},
+		{"invalid date", TypedOutput{Type: OutputDate}, "2023-02-30", nil},
+		{"bulleted list", TypedOutput{Type: OutputList}, "- go\n- rust\n2) zig\n", []string{"go", "rust", "zig"}},
+		{"comma list", TypedOutput{Type: OutputList}, "red, green , blue", []string{"red", "green", "blue"}},
+		{"json list", TypedOutput{Type: OutputList}, `Here: ["a, b", "c"]`, []string{"a, b", "c"}},
+		{"list of categories", TypedOutput{Type: OutputList, Categories: []string{"yes", "no"}}, "Yes.\nno", []string{"yes", "no"}},
+		{"list outside categories", TypedOutput{Type: OutputList, Categories: []string{"yes", "no"}}, "yes, maybe", nil},
+		{"short list", TypedOutput{Type: OutputList, MinItems: 3}, "a, b", nil},
+		{"long list", TypedOutput{Type: OutputList, MaxItems: 1}, "a, b", nil},
+		{"json", TypedOutput{Type: OutputJSON}, "```json\n{\"name\": \"gopher\", \"age\": 14}\n```", map[string]any{"name": "gopher", "age": 14.0}},
+		{"json array", TypedOutput{Type: OutputJSON}, "[1, 2]", nil},
+	} {
+		got, err := ParseOutput(tc.output, tc.text)
+		if tc.want == nil {
+			var outputErr *OutputError
+			if !errors.As(err, &outputErr) || outputErr.Type != tc.output.Type {
+				t.Errorf("%s: got %#v and error %v, want an *OutputError", tc.name, got, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %#v and error %v, want %#v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestParseOutputErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output TypedOutput
+		want   error
+	}{
+		{"unknown type", TypedOutput{Type: "integr"}, ErrOutputType},
+		{"bad pattern", TypedOutput{Type: OutputRegex, Pattern: "("}, nil},
+		{"bad fallback", TypedOutput{Type: OutputInteger, Fallback: "none"}, nil},
+	} {
+		_, err := ParseOutput(tc.output, "text")
+		var outputErr *OutputError
+		if err == nil || errors.As(err, &outputErr) || tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("%s: got error %v", tc.name, err)
+		}
+	}
+	if err := CheckOutputType(""); err != nil {
+		t.Errorf("an empty type is an error: %v", err)
+	}
+}
+
+func TestTypedOutputJSON(t *testing.T) {
+	for _, tc := range []struct {
+		output TypedOutput
+		want   string
+	}{
+		{TypedOutput{Type: OutputCategorical, Categories: []string{"yes", "no"}, Toxicity: true}, `"type":"categorical","categories":["yes","no"]`},
+		{TypedOutput{Type: OutputInteger, Categories: []string{"1"}, Pattern: `\d`, Factuality: true, Fallback: "0"}, `"type":"","categories":null,"pattern":"","consistency":false,"factuality":true`},
+	} {
+		b, err := json.Marshal(tc.output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), tc.want) || strings.Contains(string(b), "fallback") {
+			t.Errorf("%s output encoded as %s, want %s", tc.output.Type, b, tc.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// readDOCX reads a Word document, one Markdown document per top level
+// section with a source like file:///handbook.docx#section=2. Paragraphs
+// styled as headings become Markdown headings, list paragraphs become list
+// items and tables become Markdown tables. Every section gets its number,
+// heading and the page it starts on as metadata; pages are counted from
+// the page breaks Word recorded when it last laid out the file, or from
+// explicit page breaks if it recorded none.
+func readDOCX(path string, content []byte) ([]document.Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("loader: %s: %w", path, err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	body, ok := files["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("loader: %s: not a Word document", path)
+	}
+
+	levels := map[string]int{}
+	if f, ok := files["word/styles.xml"]; ok {
+		if levels, err = docxHeadingStyles(f); err != nil {
+			return nil, fmt.Errorf("loader: %s: %w", path, err)
+		}
+	}
+	blocks, err := docxBlocks(body, levels)
+	if err != nil {
+		return nil, fmt.Errorf("loader: %s: %w", path, err)
+	}
+	title := ""
+	if f, ok := files["docProps/core.xml"]; ok {
+		title = docxTitle(f)
+	}
+
+	// Split the blocks into sections at the top level headings.
+	top := 0
+	for _, b := range blocks {
+		if b.level > 0 && (top == 0 || b.level < top) {
+			top = b.level
+		}
+	}
+	uri := fileURI(path)
+	docs := []document.Document{}
+	var text strings.Builder
+	var meta map[string]string
+	flush := func() {
+		if s := strings.TrimSpace(text.String()); s != "" {
+			docs = append(docs, document.Document{
+				Source:   uri + "#section=" + meta["section"],
+				Format:   document.FormatMarkdown,
+				Content:  s,
+				Metadata: meta,
+			})
+		}
+		text.Reset()
+	}
+	section := 0
+	meta = map[string]string{"section": "0", "page": "1"}
+	for _, b := range blocks {
+		if b.level > 0 && b.level == top {
+			flush()
+			section++
+			meta = map[string]string{
+				"section": strconv.Itoa(section),
+				"heading": b.text,
+				"page":    strconv.Itoa(b.page),
+			}
+		}
+		if b.level > 0 && title == "" {
+			title = b.text
+		}
+		text.WriteString(b.markdown(top))
+		text.WriteString("\n\n")
+	}
+	flush()
+
+	if title == "" {
+		title = titleFromName(path)
+	}
+	for i := range docs {
+		docs[i].Title = title
+	}
+	return docs, nil
+}
+
+// docxBlock is a paragraph, list item or table of a Word document.
+type docxBlock struct {
+	text string
+
+	// level is the heading level, or 0 for other blocks.
+	level int
+
+	// list is the nesting depth of a list item plus one, or 0 for other
+	// blocks.
+	list int
+
+	// rows holds the cells of a table.
+	rows [][]string
+
+	page int
+}
+
+// markdown returns the block as Markdown, with the top heading level as
+// level one.
+func (b docxBlock) markdown(top int) string {
+	switch {
+	case b.rows != nil:
+		return markdownTable(b.rows)
+	case b.level > 0:
+		return strings.Repeat("#", min(b.level-top+1, 6)) + " " + b.text
+	case b.list > 0:
+		return strings.Repeat("  ", b.list-1) + "- " + b.text
+	}
+	return b.text
+}
+
+// markdownTable formats rows of cells as a Markdown table, with the first
+// row as the header.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("|")
+		for j := 0; j < width; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// docxBlocks reads the paragraphs and tables of word/document.xml.
+func docxBlocks(f *zip.File, levels map[string]int) ([]docxBlock, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	blocks := []docxBlock{}
+	var para strings.Builder
+	var block docxBlock
+	var rows [][]string
+	var cell []string
+	tables := 0
+	explicit, rendered := 0, 0
+	explicitPages := []int{}
+	start := 0
+
+	d := xml.NewDecoder(rc)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				block = docxBlock{page: rendered + 1}
+				start = explicit + 1
+			case "pStyle":
+				block.level = levels[attr(t, "val")]
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil && n < 9 {
+					block.level = n + 1
+				}
+			case "ilvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil {
+					block.list = n + 1
+				}
+			case "numPr":
+				block.list = max(block.list, 1)
+			case "t":
+				var s string
+				if err := d.DecodeElement(&s, &t); err != nil {
+					return nil, err
+				}
+				para.WriteString(s)
+			case "tab":
+				para.WriteString("\t")
+			case "br":
+				// A paragraph that starts with a break starts on the
+				// next page.
+				if attr(t, "type") == "page" {
+					explicit++
+					if para.Len() == 0 {
+						start = explicit + 1
+					}
+				} else {
+					para.WriteString(" ")
+				}
+			case "lastRenderedPageBreak":
+				rendered++
+				if para.Len() == 0 {
+					block.page = rendered + 1
+				}
+			case "tbl":
+				tables++
+				if tables == 1 {
+					rows = [][]string{}
+				}
+			case "tr":
+				if tables == 1 {
+					rows = append(rows, nil)
+				}
+			case "tc":
+				if tables == 1 {
+					cell = nil
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := strings.Join(strings.Fields(para.String()), " ")
+				switch {
+				case text == "":
+				case tables > 0:
+					cell = append(cell, text)
+				default:
+					block.text = text
+					if block.level > 0 {
+						block.list = 0
+					}
+					blocks = append(blocks, block)
+					explicitPages = append(explicitPages, start)
+				}
+			case "tc":
+				if tables == 1 && len(rows) > 0 {
+					rows[len(rows)-1] = append(rows[len(rows)-1], strings.Join(cell, " "))
+				}
+			case "tbl":
+				tables--
+				if tables == 0 && len(rows) > 0 {
+					blocks = append(blocks, docxBlock{rows: rows, page: rendered + 1})
+					explicitPages = append(explicitPages, explicit+1)
+				}
+			}
+		}
+	}
+
+	// Fall back to explicit page breaks if Word recorded no layout.
+	if rendered == 0 {
+		for i := range blocks {
+			blocks[i].page = explicitPages[i]
+		}
+	}
+	return blocks, nil
+}
+
+// docxHeadingStyles maps the IDs of the heading styles in word/styles.xml
+// to their level. The title style is level one, and "heading N" styles and
+// styles with an outline level are level N.
+func docxHeadingStyles(f *zip.File) (map[string]int, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var styles struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+			Outline *struct {
+				Val int `xml:"val,attr"`
+			} `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&styles); err != nil {
+		return nil, err
+	}
+	levels := map[string]int{}
+	for _, s := range styles.Styles {
+		name := strings.ToLower(s.Name.Val)
+		switch {
+		case name == "title":
+			levels[s.ID] = 1
+		case strings.HasPrefix(name, "heading "):
+			if n, err := strconv.Atoi(strings.TrimPrefix(name, "heading ")); err == nil {
+				levels[s.ID] = n
+			}
+		case s.Outline != nil && s.Outline.Val < 9:
+			levels[s.ID] = s.Outline.Val + 1
+		}
+	}
+	return levels, nil
+}
+
+// docxTitle returns the title in docProps/core.xml, or "".
+func docxTitle(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	var core struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&core); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(core.Title)
+}
+
+// attr returns the value of an attribute of an element by its local name.
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
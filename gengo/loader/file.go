@@ -0,0 +1,230 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// reader turns the content of a file into documents.
+type reader func(path string, content []byte) ([]document.Document, error)
+
+// readers maps the file extensions that can be loaded to their readers.
+var readers = map[string]reader{
+	".md":       readMarkdown,
+	".markdown": readMarkdown,
+	".txt":      readText,
+	".text":     readText,
+	".html":     readHTML,
+	".htm":      readHTML,
+	".pdf":      readPDF,
+	".docx":     readDOCX,
+	".go":       readGo,
+	".jsonl":    readJSONL,
+}
+
+// Extensions returns the file extensions that can be loaded, sorted.
+func Extensions() []string {
+	exts := make([]string, 0, len(readers))
+	for ext := range readers {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// File loads a single file, picking the reader by its extension. Every
+// document gets the file's path and modification time as metadata.
+type File struct {
+	Path string
+}
+
+// Load implements Loader.
+func (f *File) Load(ctx context.Context) ([]document.Document, error) {
+	read, ok := readers[strings.ToLower(filepath.Ext(f.Path))]
+	if !ok {
+		return nil, fmt.Errorf("loader: %s: unsupported file type", f.Path)
+	}
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := read(f.Path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(f.Path)
+	if err != nil {
+		abs = f.Path
+	}
+	for i := range docs {
+		d := &docs[i]
+		if d.Source == "" {
+			d.Source = fileURI(f.Path)
+		}
+		if d.Metadata == nil {
+			d.Metadata = map[string]string{}
+		}
+		d.Metadata["path"] = abs
+		d.Metadata["modified"] = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	return docs, nil
+}
+
+// Dir loads every file under a directory with one of the extensions.
+// Hidden directories, vendor, testdata and node_modules are skipped, as
+// are Go test files.
+type Dir struct {
+	Path string
+
+	// Extensions are the file extensions to load, like ".md". If empty
+	// every extension with a reader is loaded.
+	Extensions []string
+
+	// GoDeclarations loads the Go packages in the directories with
+	// GoDeclarations, one document per declaration, rather than one
+	// document per Go file.
+	GoDeclarations bool
+}
+
+// Load implements Loader.
+func (d *Dir) Load(ctx context.Context) ([]document.Document, error) {
+	exts := d.Extensions
+	if len(exts) == 0 {
+		exts = Extensions()
+	}
+	wanted := map[string]bool{}
+	for _, ext := range exts {
+		wanted[strings.ToLower(ext)] = true
+	}
+
+	docs := []document.Document{}
+	err := filepath.WalkDir(d.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != d.Path && skipDir(name) {
+				return filepath.SkipDir
+			}
+			if d.GoDeclarations && wanted[".go"] {
+				return d.loadPackage(ctx, path, &docs)
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		if !wanted[ext] || strings.HasSuffix(name, "_test.go") || ext == ".go" && d.GoDeclarations {
+			return nil
+		}
+		loaded, err := (&File{Path: path}).Load(ctx)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, loaded...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// loadPackage adds the declarations of the Go package in dir, if there is
+// one. Directories of only tests are skipped.
+func (d *Dir) loadPackage(ctx context.Context, dir string, docs *[]document.Document) error {
+	loaded, err := (&GoDeclarations{Dir: dir}).Load(ctx)
+	if errors.Is(err, ErrNoGoFiles) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	*docs = append(*docs, loaded...)
+	return nil
+}
+
+// skipDir reports whether a directory should not be walked.
+func skipDir(name string) bool {
+	switch name {
+	case "vendor", "testdata", "node_modules":
+		return true
+	}
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// readMarkdown reads a Markdown file, titled by its first level one
+// heading.
+func readMarkdown(path string, content []byte) ([]document.Document, error) {
+	text := string(content)
+	return []document.Document{{
+		Title:   markdownTitle(text, path),
+		Format:  document.FormatMarkdown,
+		Content: text,
+	}}, nil
+}
+
+// markdownTitle returns the text of the first level one heading, or a
+// title made from the file name.
+func markdownTitle(text string, path string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:])
+		}
+	}
+	return titleFromName(path)
+}
+
+// readText reads a plain text file.
+func readText(path string, content []byte) ([]document.Document, error) {
+	return []document.Document{{
+		Title:   titleFromName(path),
+		Format:  document.FormatText,
+		Content: string(content),
+	}}, nil
+}
+
+// readHTML reads an HTML file, converting it to Markdown. It is titled by
+// its <title> element.
+func readHTML(path string, content []byte) ([]document.Document, error) {
+	html := string(content)
+	markdown, err := md.NewConverter("", true, nil).ConvertString(html)
+	if err != nil {
+		return nil, fmt.Errorf("loader: %s: %w", path, err)
+	}
+	return []document.Document{{
+		Title:   htmlTitle(html, path),
+		Format:  document.FormatMarkdown,
+		Content: markdown,
+	}}, nil
+}
+
+// htmlTitle returns the <title> of an HTML page, or a title made from the
+// file name.
+func htmlTitle(html string, path string) string {
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err == nil {
+		if title := strings.TrimSpace(page.Find("title").First().Text()); title != "" {
+			return title
+		}
+	}
+	return titleFromName(path)
+}
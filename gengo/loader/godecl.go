@@ -0,0 +1,224 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// Kinds of Go declaration.
+const (
+	KindPackage = "package"
+	KindFunc    = "func"
+	KindMethod  = "method"
+	KindType    = "type"
+	KindConst   = "const"
+	KindVar     = "var"
+)
+
+// GoDeclarations loads the Go package in a directory with one document per
+// declaration rather than per file, so functions are never cut in half.
+// Each function, method, type and group of constants or variables becomes
+// a document holding its doc comment and source, headed by the package
+// clause and import path, with a source like file:///client.go#L42. A
+// further document holds the package documentation.
+//
+// Every document gets as metadata the package name and import path, the
+// declaration's kind ("func", "method", "type", "const", "var" or
+// "package"), its name like Client.Complete, its signature, and its file
+// and line.
+type GoDeclarations struct {
+	Dir string
+
+	// Unexported includes unexported declarations.
+	Unexported bool
+
+	// Tests includes the declarations in _test.go files of the same
+	// package.
+	Tests bool
+}
+
+// Load implements Loader.
+func (g *GoDeclarations) Load(ctx context.Context) ([]document.Document, error) {
+	paths, err := filepath.Glob(filepath.Join(g.Dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	// Parse the files of the package, keeping their source to cut the
+	// declarations from.
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	sources := map[string][]byte{}
+	for _, path := range paths {
+		if !g.Tests && strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 && f.Name.Name != files[0].Name.Name {
+			continue
+		}
+		files = append(files, f)
+		sources[path] = src
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoGoFiles, g.Dir)
+	}
+
+	importPath := importPath(g.Dir)
+	mode := doc.PreserveAST
+	if g.Unexported {
+		mode |= doc.AllDecls
+	}
+	pkg, err := doc.NewFromFiles(fset, files, importPath, mode)
+	if err != nil {
+		return nil, err
+	}
+	r := &declReader{fset: fset, sources: sources, pkg: pkg, importPath: importPath}
+
+	// The package documentation, then the declarations in the order go
+	// doc lists them.
+	for _, f := range files {
+		if f.Doc != nil && pkg.Doc != "" {
+			r.add(KindPackage, pkg.Name, pkg.Doc, "package "+pkg.Name, f.Package, "")
+			break
+		}
+	}
+	r.values(pkg.Consts, KindConst)
+	r.values(pkg.Vars, KindVar)
+	for _, f := range pkg.Funcs {
+		r.fn(f, KindFunc, f.Name)
+	}
+	for _, t := range pkg.Types {
+		r.decl(t.Decl, KindType, t.Name, t.Doc)
+		r.values(t.Consts, KindConst)
+		r.values(t.Vars, KindVar)
+		for _, f := range t.Funcs {
+			r.fn(f, KindFunc, f.Name)
+		}
+		for _, m := range t.Methods {
+			r.fn(m, KindMethod, t.Name+"."+m.Name)
+		}
+	}
+	return r.docs, nil
+}
+
+// declReader collects the declarations of a package as documents.
+type declReader struct {
+	fset       *token.FileSet
+	sources    map[string][]byte
+	pkg        *doc.Package
+	importPath string
+	docs       []document.Document
+}
+
+// fn adds a function or method.
+func (r *declReader) fn(f *doc.Func, kind string, name string) {
+	if f.Decl == nil {
+		return
+	}
+	r.decl(f.Decl, kind, name, f.Doc)
+}
+
+// values adds groups of constants or variables.
+func (r *declReader) values(values []*doc.Value, kind string) {
+	for _, v := range values {
+		r.decl(v.Decl, kind, strings.Join(v.Names, ", "), v.Doc)
+	}
+}
+
+// decl adds a declaration with its doc comment.
+func (r *declReader) decl(decl ast.Decl, kind string, name string, comment string) {
+	var code, signature string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		code = r.source(d.Pos(), d.End())
+		signature = code
+		if d.Body != nil {
+			signature = r.source(d.Pos(), d.Body.Lbrace)
+		}
+	case *ast.GenDecl:
+		// go/doc gives each type of a group its own declaration, which
+		// starts at the type's spec.
+		if !d.Lparen.IsValid() && len(d.Specs) == 1 && d.TokPos == d.Specs[0].Pos() {
+			code = d.Tok.String() + " " + r.source(d.Specs[0].Pos(), d.Specs[0].End())
+		} else {
+			code = r.source(d.Pos(), d.End())
+		}
+		signature = d.Tok.String() + " " + name
+		if ts, ok := d.Specs[0].(*ast.TypeSpec); ok && len(d.Specs) == 1 {
+			signature = "type " + strings.TrimSpace(strings.SplitN(r.source(ts.Pos(), ts.End()), "{", 2)[0])
+		}
+	}
+	r.add(kind, name, comment, strings.TrimSpace(signature), decl.Pos(), code)
+}
+
+// add adds a document for a declaration.
+func (r *declReader) add(kind string, name string, comment string, signature string, pos token.Pos, code string) {
+	position := r.fset.Position(pos)
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s", r.pkg.Name)
+	if r.importPath != "" {
+		fmt.Fprintf(&b, " // import %q", r.importPath)
+	}
+	b.WriteString("\n\n")
+	for _, line := range strings.Split(strings.TrimRight(comment, "\n"), "\n") {
+		if comment != "" {
+			b.WriteString(strings.TrimRight("// "+line, " ") + "\n")
+		}
+	}
+	b.WriteString(code)
+
+	title := r.pkg.Name
+	if kind != KindPackage {
+		title += "." + name
+	}
+	path, err := filepath.Abs(position.Filename)
+	if err != nil {
+		path = position.Filename
+	}
+	metadata := map[string]string{
+		"path":        path,
+		"package":     r.pkg.Name,
+		"kind":        kind,
+		"declaration": name,
+		"signature":   signature,
+		"file":        filepath.Base(position.Filename),
+		"line":        strconv.Itoa(position.Line),
+	}
+	if r.importPath != "" {
+		metadata["import_path"] = r.importPath
+	}
+	r.docs = append(r.docs, document.Document{
+		Source:   fmt.Sprintf("%s#L%d", fileURI(position.Filename), position.Line),
+		Title:    title,
+		Format:   document.FormatGo,
+		Content:  strings.TrimSpace(b.String()),
+		Metadata: metadata,
+	})
+}
+
+// source returns the source between two positions in the same file.
+func (r *declReader) source(start token.Pos, end token.Pos) string {
+	file := r.fset.File(start)
+	src := r.sources[file.Name()]
+	return string(src[file.Offset(start):file.Offset(end)])
+}
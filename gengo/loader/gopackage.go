@@ -0,0 +1,114 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// ErrNoGoFiles is returned when a directory holds no Go files to load,
+// other than tests if they are not wanted.
+var ErrNoGoFiles = errors.New("loader: no Go files")
+
+// GoPackage loads the Go source files of the package in a directory, one
+// document per file. Every document gets the package name and, if the
+// directory is inside a module, its import path as metadata.
+type GoPackage struct {
+	Dir string
+
+	// Tests includes _test.go files.
+	Tests bool
+}
+
+// Load implements Loader.
+func (p *GoPackage) Load(ctx context.Context) ([]document.Document, error) {
+	files, err := filepath.Glob(filepath.Join(p.Dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	docs := []document.Document{}
+	for _, file := range files {
+		if !p.Tests && strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		loaded, err := (&File{Path: file}).Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, loaded...)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoGoFiles, p.Dir)
+	}
+	return docs, nil
+}
+
+// readGo reads a Go source file.
+func readGo(file string, content []byte) ([]document.Document, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), file, content, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
+	}
+	metadata := map[string]string{"package": f.Name.Name}
+	title := "package " + f.Name.Name
+	if importPath := importPath(filepath.Dir(file)); importPath != "" {
+		metadata["import_path"] = importPath
+		title = importPath
+	}
+	return []document.Document{{
+		Title:    title + " (" + filepath.Base(file) + ")",
+		Format:   document.FormatGo,
+		Content:  string(content),
+		Metadata: metadata,
+	}}, nil
+}
+
+// importPath returns the import path of the package in dir, found from the
+// module path of the nearest go.mod above it, or "" if there is none.
+func importPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for root := dir; ; root = filepath.Dir(root) {
+		if module := modulePath(filepath.Join(root, "go.mod")); module != "" {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(module, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// modulePath returns the module path declared in a go.mod file, or "" if
+// the file does not exist.
+func modulePath(gomod string) string {
+	content, err := os.ReadFile(gomod)
+	if err != nil {
+		return ""
+	}
+	lines := bufio.NewScanner(bytes.NewReader(content))
+	for lines.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(lines.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// Default JSONL field names.
+const (
+	DefaultTextField   = "text"
+	DefaultTitleField  = "title"
+	DefaultSourceField = "url"
+)
+
+// JSONL loads an export with one JSON object per line, one document per
+// object. Other scalar fields of each object become its metadata.
+type JSONL struct {
+	Path string
+
+	// TextField, TitleField and SourceField name the fields holding the
+	// content, title and source URI of each object. Empty names use the
+	// defaults. Objects without a source get the file's URI with their
+	// line number, like file:///export.jsonl#L12.
+	TextField   string
+	TitleField  string
+	SourceField string
+}
+
+// Load implements Loader.
+func (j *JSONL) Load(ctx context.Context) ([]document.Document, error) {
+	content, err := os.ReadFile(j.Path)
+	if err != nil {
+		return nil, err
+	}
+	return j.read(content)
+}
+
+// readJSONL reads a JSONL file with the default field names.
+func readJSONL(path string, content []byte) ([]document.Document, error) {
+	return (&JSONL{Path: path}).read(content)
+}
+
+// read parses the lines of the export.
+func (j *JSONL) read(content []byte) ([]document.Document, error) {
+	textField := or(j.TextField, DefaultTextField)
+	titleField := or(j.TitleField, DefaultTitleField)
+	sourceField := or(j.SourceField, DefaultSourceField)
+
+	docs := []document.Document{}
+	lines := bufio.NewScanner(bytes.NewReader(content))
+	lines.Buffer(nil, 64<<20)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		var object map[string]any
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return nil, fmt.Errorf("loader: %s:%d: %w", j.Path, n, err)
+		}
+		text, ok := object[textField].(string)
+		if !ok {
+			return nil, fmt.Errorf("loader: %s:%d: no %q string field", j.Path, n, textField)
+		}
+
+		d := document.Document{
+			Format:   document.FormatText,
+			Content:  text,
+			Metadata: map[string]string{},
+		}
+		d.Title, _ = object[titleField].(string)
+		d.Source, _ = object[sourceField].(string)
+		if d.Source == "" {
+			d.Source = fmt.Sprintf("%s#L%d", fileURI(j.Path), n)
+		}
+		for k, v := range object {
+			switch v.(type) {
+			case string, float64, bool:
+				if k != textField && k != titleField && k != sourceField {
+					d.Metadata[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		docs = append(docs, d)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("loader: %s: %w", j.Path, err)
+	}
+	return docs, nil
+}
+
+// or returns value, or fallback if value is empty.
+func or(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
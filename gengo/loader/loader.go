@@ -0,0 +1,67 @@
+// Package loader reads documents to index from local files and
+// directories, Go packages, JSONL exports, web pages and crawled sites,
+// recording where each one came from.
+package loader
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// Loader loads documents from a source.
+type Loader interface {
+	Load(ctx context.Context) ([]document.Document, error)
+}
+
+// LoadAll loads the documents of every loader in order.
+func LoadAll(ctx context.Context, loaders ...Loader) ([]document.Document, error) {
+	docs := []document.Document{}
+	for _, l := range loaders {
+		loaded, err := l.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, loaded...)
+	}
+	return docs, nil
+}
+
+// For returns the loader for a command line argument: a Website for http
+// and https URLs, a Dir that loads Go packages by declaration for
+// directories, and a File for anything else.
+func For(target string) (Loader, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return &Website{URL: target}, nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Dir{Path: target, GoDeclarations: true}, nil
+	}
+	return &File{Path: target}, nil
+}
+
+// fileURI returns the file URI of a path.
+func fileURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// titleFromName returns a title made from a file name, like "getting
+// started" for getting-started.md.
+func titleFromName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == ' '
+	}), " ")
+}
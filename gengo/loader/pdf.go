@@ -0,0 +1,163 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// headingScale is how much larger than the body text a line's font must be
+// for the line to be taken as a heading.
+const headingScale = 1.15
+
+// readPDF reads a PDF file, one Markdown document per page with a source
+// like file:///report.pdf#page=3. Lines set in a larger font than the
+// body text become headings, the largest size a level one heading. Every
+// page gets its number and the heading of the section it is in as
+// metadata.
+func readPDF(path string, content []byte) (docs []document.Document, err error) {
+	// The PDF reader panics on malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			docs, err = nil, fmt.Errorf("loader: %s: %v", path, r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("loader: %s: %w", path, err)
+	}
+	pages := make([][]pdfLine, r.NumPage())
+	for i := range pages {
+		page := r.Page(i + 1)
+		if page.V.IsNull() {
+			continue
+		}
+		pages[i] = pdfLines(page.Content().Text)
+	}
+	levels := headingLevels(pages)
+
+	title := strings.TrimSpace(r.Trailer().Key("Info").Key("Title").Text())
+	uri := fileURI(path)
+	section := ""
+	for i, lines := range pages {
+		pageSection := section
+		var b strings.Builder
+		for j, line := range lines {
+			if level := levels[line.size]; level > 0 && len(line.text) < 120 {
+				if j == 0 || pageSection == "" {
+					pageSection = line.text
+				}
+				section = line.text
+				if title == "" {
+					title = line.text
+				}
+				fmt.Fprintf(&b, "\n%s %s\n\n", strings.Repeat("#", level), line.text)
+				continue
+			}
+			b.WriteString(line.text)
+			b.WriteString("\n")
+		}
+		text := strings.TrimSpace(b.String())
+		if text == "" {
+			continue
+		}
+		d := document.Document{
+			Source:   fmt.Sprintf("%s#page=%d", uri, i+1),
+			Format:   document.FormatMarkdown,
+			Content:  text,
+			Metadata: map[string]string{"page": strconv.Itoa(i + 1)},
+		}
+		if pageSection != "" {
+			d.Metadata["section"] = pageSection
+		}
+		docs = append(docs, d)
+	}
+
+	if title == "" {
+		title = titleFromName(path)
+	}
+	for i := range docs {
+		docs[i].Title = title
+	}
+	return docs, nil
+}
+
+// pdfLine is a line of text on a PDF page, with the largest font size in
+// it rounded to a point.
+type pdfLine struct {
+	text string
+	size float64
+}
+
+// pdfLines assembles the characters of a page into lines, top to bottom,
+// adding spaces where there are gaps between characters.
+func pdfLines(chars []pdf.Text) []pdfLine {
+	chars = append([]pdf.Text(nil), chars...)
+	sort.SliceStable(chars, func(i, j int) bool {
+		if yi, yj := math.Round(chars[i].Y), math.Round(chars[j].Y); yi != yj {
+			return yi > yj
+		}
+		return chars[i].X < chars[j].X
+	})
+
+	lines := []pdfLine{}
+	var b strings.Builder
+	size, y, end := 0.0, 0.0, 0.0
+	flush := func() {
+		if text := strings.Join(strings.Fields(b.String()), " "); text != "" {
+			lines = append(lines, pdfLine{text: text, size: math.Round(size)})
+		}
+		b.Reset()
+		size = 0
+	}
+	for i, c := range chars {
+		if i > 0 && math.Abs(c.Y-y) > max(c.FontSize, size)/2 {
+			flush()
+		} else if i > 0 && c.X-end > c.FontSize*0.2 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(c.S)
+		size = max(size, c.FontSize)
+		y, end = c.Y, c.X+c.W
+	}
+	flush()
+	return lines
+}
+
+// headingLevels maps the font sizes used for headings to their level. The
+// body size is the one most characters are set in, and the three largest
+// sizes above it are levels one to three.
+func headingLevels(pages [][]pdfLine) map[float64]int {
+	counts := map[float64]int{}
+	for _, lines := range pages {
+		for _, line := range lines {
+			counts[line.size] += len(line.text)
+		}
+	}
+	body, most := 0.0, 0
+	for size, n := range counts {
+		if n > most || n == most && size < body {
+			body, most = size, n
+		}
+	}
+
+	larger := []float64{}
+	for size := range counts {
+		if size > body*headingScale {
+			larger = append(larger, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(larger)))
+	levels := map[float64]int{}
+	for i, size := range larger {
+		levels[size] = min(i+1, 3)
+	}
+	return levels
+}
@@ -0,0 +1,61 @@
+package loader
+
+import (
+	"context"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/crawl"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/extract"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/ingest"
+)
+
+// Website loads the main content of a single web page as Markdown,
+// optionally keeping only the part between the Start and End strings.
+type Website struct {
+	URL   string
+	Start string
+	End   string
+
+	// Extractor, if set, extracts the content with its rules instead of
+	// Start and End.
+	Extractor *extract.Extractor
+}
+
+// Load implements Loader.
+func (w *Website) Load(ctx context.Context) ([]document.Document, error) {
+	e := w.Extractor
+	if e == nil {
+		e = extract.New(extract.Rule{Start: w.Start, End: w.End})
+	}
+	content, err := ingest.WebsiteContent(ctx, w.URL, e)
+	if err != nil {
+		return nil, err
+	}
+	title := content.Title
+	if title == "" {
+		title = markdownTitle(content.Markdown, w.URL)
+	}
+	return []document.Document{{
+		Source:  w.URL,
+		Title:   title,
+		Format:  document.FormatMarkdown,
+		Content: content.Markdown,
+	}}, nil
+}
+
+// Site loads every page of a website that a crawler reaches from URL.
+type Site struct {
+	URL string
+
+	// Crawler crawls the site. If nil, crawl.New() is used.
+	Crawler *crawl.Crawler
+}
+
+// Load implements Loader.
+func (s *Site) Load(ctx context.Context) ([]document.Document, error) {
+	c := s.Crawler
+	if c == nil {
+		c = crawl.New()
+	}
+	return c.Crawl(ctx, s.URL)
+}
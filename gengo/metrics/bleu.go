@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"math"
+)
+
+// maxBLEUOrder is the largest n-gram order used by SelfBLEU.
+const maxBLEUOrder = 4
+
+// SelfBLEU scores each sample with BLEU-4 against all of the other samples
+// as references and returns the mean. High values mean the samples copy
+// each other; low values mean they are diverse.
+func SelfBLEU(samples []string) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	tokenized := make([][]string, len(samples))
+	for i, s := range samples {
+		tokenized[i] = words(s)
+	}
+
+	total := 0.0
+	for i, hypothesis := range tokenized {
+		references := make([][]string, 0, len(tokenized)-1)
+		references = append(references, tokenized[:i]...)
+		references = append(references, tokenized[i+1:]...)
+		total += bleu(hypothesis, references)
+	}
+	return total / float64(len(samples))
+}
+
+// bleu computes sentence level BLEU of a hypothesis against references
+// with clipped n-gram precision, a brevity penalty and epsilon smoothing
+// for n-gram orders without any matches.
+func bleu(hypothesis []string, references [][]string) float64 {
+	if len(hypothesis) == 0 {
+		return 0
+	}
+
+	logSum := 0.0
+	orders := 0
+	for n := 1; n <= maxBLEUOrder && n <= len(hypothesis); n++ {
+
+		// Count the hypothesis n-grams and the most times each appears in
+		// any single reference.
+		counts := map[string]int{}
+		for _, g := range ngrams(hypothesis, n) {
+			counts[g]++
+		}
+		maxRef := map[string]int{}
+		for _, ref := range references {
+			refCounts := map[string]int{}
+			for _, g := range ngrams(ref, n) {
+				refCounts[g]++
+			}
+			for g, c := range refCounts {
+				if c > maxRef[g] {
+					maxRef[g] = c
+				}
+			}
+		}
+
+		// Clip the matches by the reference counts.
+		matches, total := 0, 0
+		for g, c := range counts {
+			matches += min(c, maxRef[g])
+			total += c
+		}
+		precision := float64(matches) / float64(total)
+		if matches == 0 {
+			precision = 0.1 / float64(total)
+		}
+		logSum += math.Log(precision)
+		orders++
+	}
+
+	// Penalize hypotheses shorter than the closest reference length.
+	closest := -1
+	for _, ref := range references {
+		if closest < 0 || abs(len(ref)-len(hypothesis)) < abs(closest-len(hypothesis)) {
+			closest = len(ref)
+		}
+	}
+	penalty := 1.0
+	if len(hypothesis) < closest {
+		penalty = math.Exp(1 - float64(closest)/float64(len(hypothesis)))
+	}
+
+	return penalty * math.Exp(logSum/float64(orders))
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,116 @@
+// Package metrics measures the diversity and quality of a set of sampled
+// completions, so that parameters like temperature can be chosen with data.
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// words splits text into lower cased whitespace separated words.
+func words(text string) []string {
+	return strings.Fields(strings.ToLower(text))
+}
+
+// ngrams returns the n-grams of the words, joined by a space.
+func ngrams(ws []string, n int) []string {
+	if n <= 0 || len(ws) < n {
+		return nil
+	}
+	grams := make([]string, 0, len(ws)-n+1)
+	for i := 0; i+n <= len(ws); i++ {
+		grams = append(grams, strings.Join(ws[i:i+n], " "))
+	}
+	return grams
+}
+
+// DistinctN is the number of distinct n-grams across all of the samples
+// divided by the total number of n-grams. It is 1 when no n-gram repeats
+// and approaches 0 as the samples repeat themselves and each other.
+func DistinctN(samples []string, n int) float64 {
+	total := 0
+	distinct := map[string]struct{}{}
+	for _, s := range samples {
+		for _, g := range ngrams(words(s), n) {
+			distinct[g] = struct{}{}
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(len(distinct)) / float64(total)
+}
+
+// UniqueRatio is the fraction of samples that are distinct after trimming
+// whitespace and ignoring case.
+func UniqueRatio(samples []string) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	unique := map[string]struct{}{}
+	for _, s := range samples {
+		unique[strings.ToLower(strings.TrimSpace(s))] = struct{}{}
+	}
+	return float64(len(unique)) / float64(len(samples))
+}
+
+// AverageTokens is the mean length of the samples in tokens.
+func AverageTokens(samples []string) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0
+	for _, s := range samples {
+		total += tokens.Count(s)
+	}
+	return float64(total) / float64(len(samples))
+}
+
+// TruncationRate is the fraction of raw completions that contained one of
+// the stop tokens and so were cut short in post processing.
+func TruncationRate(raw []string, stop []string) float64 {
+	if len(raw) == 0 {
+		return 0
+	}
+	truncated := 0
+	for _, r := range raw {
+		for _, s := range stop {
+			if strings.Contains(r, s) {
+				truncated++
+				break
+			}
+		}
+	}
+	return float64(truncated) / float64(len(raw))
+}
+
+// PairwiseSimilarity embeds the samples and returns the mean cosine
+// similarity over every pair. Values near 1 mean the samples say the same
+// thing, even if they use different words.
+func PairwiseSimilarity(ctx context.Context, e embedding.Embedder, samples []string) (float64, error) {
+	if len(samples) < 2 {
+		return 0, nil
+	}
+	vectors, err := embedding.EmbedAll(ctx, e, samples, embedding.DefaultBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0.0
+	pairs := 0
+	for i := 0; i < len(vectors); i++ {
+		for j := i + 1; j < len(vectors); j++ {
+			similarity, err := embedding.CosineSimilarity(vectors[i], vectors[j])
+			if err != nil {
+				return 0, err
+			}
+			total += similarity
+			pairs++
+		}
+	}
+	return total / float64(pairs), nil
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// Summary is the set of metrics computed over the samples generated with
+// one setting of the parameters.
+type Summary struct {
+	AverageTokens  float64 `json:"average_tokens"`
+	Unique         float64 `json:"unique"`
+	Distinct1      float64 `json:"distinct_1"`
+	Distinct2      float64 `json:"distinct_2"`
+	SelfBLEU       float64 `json:"self_bleu"`
+	TruncationRate float64 `json:"truncation_rate"`
+
+	// Similarity is the mean pairwise embedding similarity. It is nil when
+	// no Embedder was given.
+	Similarity *float64 `json:"embedding_similarity,omitempty"`
+}
+
+// Summarize computes every metric over the post processed outputs. The
+// raw completions are used for the stop token truncation rate. The
+// embedding similarity is only computed when e is not nil; if it fails the
+// other metrics are still returned along with the error.
+func Summarize(ctx context.Context, outputs []string, raw []string, stop []string, e embedding.Embedder) (Summary, error) {
+	s := Summary{
+		AverageTokens:  AverageTokens(outputs),
+		Unique:         UniqueRatio(outputs),
+		Distinct1:      DistinctN(outputs, 1),
+		Distinct2:      DistinctN(outputs, 2),
+		SelfBLEU:       SelfBLEU(outputs),
+		TruncationRate: TruncationRate(raw, stop),
+	}
+	if e == nil {
+		return s, nil
+	}
+
+	similarity, err := PairwiseSimilarity(ctx, e, outputs)
+	if err != nil {
+		return s, err
+	}
+	s.Similarity = &similarity
+	return s, nil
+}
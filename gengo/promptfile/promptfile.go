@@ -0,0 +1,185 @@
+// Package promptfile reads prompt files: a YAML front-matter block with
+// the model and parameters, followed by a text/template prompt body.
+//
+//	---
+//	model: WizardCoder
+//	temperature: 0.1
+//	max_tokens: 20
+//	stop: ["#", "import"]
+//	output:
+//	  type: categorical
+//	  categories: [POS, NEU, NEG]
+//	guards:
+//	  toxicity: true
+//	---
+//	### Instruction:
+//	Respond with a sentiment label for the text in the below input.
+//
+//	### Input:
+//	{{.text}}
+//
+//	### Response:
+//
+// A file without front matter is treated as a bare template that uses the
+// default model and parameters, so plain .txt prompts still work.
+package promptfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// delimiter opens and closes the front-matter block.
+const delimiter = "---"
+
+// Output constrains the type of the completion.
+type Output struct {
+	Type       string   `yaml:"type"`
+	Categories []string `yaml:"categories"`
+	Pattern    string   `yaml:"pattern"`
+	Fallback   string   `yaml:"fallback"`
+	Min        *float64 `yaml:"min"`
+	Max        *float64 `yaml:"max"`
+	MinItems   int      `yaml:"min_items"`
+	MaxItems   int      `yaml:"max_items"`
+}
+
+// Guards enables the checks run against the completion.
+type Guards struct {
+	Toxicity    bool `yaml:"toxicity"`
+	Factuality  bool `yaml:"factuality"`
+	Consistency bool `yaml:"consistency"`
+}
+
+// File is a parsed prompt file.
+type File struct {
+	Model       string   `yaml:"model"`
+	Temperature float64  `yaml:"temperature"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Stop        []string `yaml:"stop"`
+	Output      Output   `yaml:"output"`
+	Guards      Guards   `yaml:"guards"`
+
+	// Template is the prompt body that follows the front matter.
+	Template string `yaml:"-"`
+
+	tmpl *template.Template
+}
+
+// Load reads and parses the prompt file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses the contents of a prompt file.
+func Parse(data []byte) (*File, error) {
+	front, body, err := split(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the front matter, rejecting keys we do not know about so
+	// that typos like "max_token" do not silently fall back to defaults.
+	var f File
+	if front != "" {
+		dec := yaml.NewDecoder(strings.NewReader(front))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+		if err := llm.CheckOutputType(f.Output.Type); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+	}
+	if f.Model == "" {
+		f.Model = llm.DefaultModel
+	}
+
+	// Parse the body so template errors show up when the file is loaded.
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	f.Template = body
+	f.tmpl = tmpl
+
+	return &f, nil
+}
+
+// split separates the front matter from the template body.
+func split(data string) (front string, body string, err error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	if !strings.HasPrefix(data, delimiter+"\n") {
+		return "", data, nil
+	}
+	rest := data[len(delimiter)+1:]
+
+	// The front matter ends at the first line that is only the delimiter.
+	for offset := 0; offset <= len(rest); {
+		line := rest[offset:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimRight(line, " \t") == delimiter {
+			body := rest[offset+len(line):]
+			body = strings.TrimPrefix(body, "\n")
+			return rest[:offset], body, nil
+		}
+		offset += len(line) + 1
+	}
+	return "", "", errors.New("front matter is not closed with ---")
+}
+
+// Render executes the template body with the given variables.
+func (f *File) Render(vars map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Request renders the prompt and builds the completion request described
+// by the file.
+func (f *File) Request(vars map[string]string) (llm.CompletionRequest, error) {
+	prompt, err := f.Render(vars)
+	if err != nil {
+		return llm.CompletionRequest{}, err
+	}
+	request := llm.CompletionRequest{
+		Model:       f.Model,
+		Prompt:      prompt,
+		MaxTokens:   f.MaxTokens,
+		Temperature: f.Temperature,
+		Stop:        f.Stop,
+		Output: llm.TypedOutput{
+			Type:        f.Output.Type,
+			Categories:  f.Output.Categories,
+			Pattern:     f.Output.Pattern,
+			Fallback:    f.Output.Fallback,
+			Min:         f.Output.Min,
+			Max:         f.Output.Max,
+			MinItems:    f.Output.MinItems,
+			MaxItems:    f.Output.MaxItems,
+			Consistency: f.Guards.Consistency,
+			Factuality:  f.Guards.Factuality,
+			Toxicity:    f.Guards.Toxicity,
+		},
+	}
+	return request, nil
+}
@@ -0,0 +1,166 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+)
+
+// Defaults for a Grounder.
+const (
+	DefaultSentenceThreshold = 0.65
+	DefaultMinSupport        = 0.5
+)
+
+// SentenceSupport is how well one sentence of an answer is backed by the
+// context.
+type SentenceSupport struct {
+	Sentence   string  `json:"sentence"`
+	Similarity float64 `json:"similarity"`
+	Supported  bool    `json:"supported"`
+}
+
+// Grounding is the result of checking an answer against its context.
+type Grounding struct {
+	Grounded bool `json:"grounded"`
+
+	// Support is the fraction of the answer's sentences supported by the
+	// context.
+	Support   float64           `json:"support"`
+	Sentences []SentenceSupport `json:"sentences,omitempty"`
+
+	// Judge is the verdict of the judge model, if one was asked.
+	Judge string `json:"judge,omitempty"`
+}
+
+// Grounder checks that answers are supported by the context they were
+// generated from. Every sentence of the answer is compared with every
+// sentence of the context by embedding similarity, and if a Judge is set
+// a model is also asked whether the answer follows from the context.
+type Grounder struct {
+	Embedder embedding.Embedder
+
+	// SentenceThreshold is the similarity at which an answer sentence
+	// counts as supported by a context sentence.
+	SentenceThreshold float64
+
+	// MinSupport is the fraction of answer sentences that must be
+	// supported for the answer to be grounded.
+	MinSupport float64
+
+	// Judge, if set, is asked with JudgeModel whether the context
+	// supports the answer. A "no" makes the answer ungrounded whatever
+	// the similarities.
+	Judge      llm.Completer
+	JudgeModel string
+}
+
+// NewGrounder returns a Grounder that compares embeddings with the default
+// thresholds and no judge.
+func NewGrounder(e embedding.Embedder) *Grounder {
+	return &Grounder{
+		Embedder:          e,
+		SentenceThreshold: DefaultSentenceThreshold,
+		MinSupport:        DefaultMinSupport,
+	}
+}
+
+// JudgePromptTemplate is a template that asks whether an answer is
+// supported by the context.
+func JudgePromptTemplate(context, question, answer string) string {
+	return fmt.Sprintf(`### Instruction:
+Read the context below, then the question and the answer given to it. Is every claim in the answer stated in or directly implied by the context? Answer "yes" or "no".
+
+### Input:
+Context: "%s"
+
+Question: "%s"
+
+Answer: "%s"
+
+### Response:
+`, context, question, answer)
+}
+
+// Check compares the answer with the context it was generated from. An
+// answer that is only the Fallback is always grounded, and the rest of one
+// that also gives it is checked without it.
+func (g *Grounder) Check(ctx context.Context, passage string, question string, answer string) (*Grounding, error) {
+	if IsFallback(answer) {
+		return &Grounding{Grounded: true, Support: 1}, nil
+	}
+	answer = WithoutFallback(answer)
+	answerSentences := split.SplitSentences(answer)
+	contextSentences := split.SplitSentences(passage)
+	if len(answerSentences) == 0 {
+		return &Grounding{Grounded: true, Support: 1}, nil
+	}
+	if len(contextSentences) == 0 {
+		return &Grounding{}, nil
+	}
+
+	// Embed the sentences of both in one go.
+	texts := append(append([]string{}, answerSentences...), contextSentences...)
+	vectors, err := embedding.EmbedAll(ctx, g.Embedder, texts, embedding.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	answerVectors, contextVectors := vectors[:len(answerSentences)], vectors[len(answerSentences):]
+
+	// Find the closest context sentence to each answer sentence.
+	grounding := Grounding{Sentences: make([]SentenceSupport, len(answerSentences))}
+	supported := 0
+	for i, av := range answerVectors {
+		best := -1.0
+		for _, cv := range contextVectors {
+			similarity, err := embedding.CosineSimilarity(av, cv)
+			if err != nil {
+				return nil, err
+			}
+			best = max(best, similarity)
+		}
+		s := SentenceSupport{
+			Sentence:   answerSentences[i],
+			Similarity: best,
+			Supported:  best >= g.SentenceThreshold,
+		}
+		if s.Supported {
+			supported++
+		}
+		grounding.Sentences[i] = s
+	}
+	grounding.Support = float64(supported) / float64(len(answerSentences))
+	grounding.Grounded = grounding.Support >= g.MinSupport
+
+	// Ask the judge.
+	if g.Judge != nil {
+		request := llm.CompletionRequest{
+			Prompt: JudgePromptTemplate(passage, question, answer),
+			Model:  g.JudgeModel,
+			Output: llm.TypedOutput{
+				Type:       llm.OutputCategorical,
+				Categories: []string{"yes", "no"},
+				Fallback:   "no",
+			},
+		}
+		response, err := g.Judge.Complete(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		verdict := response.Choices[0].Value
+		if verdict == nil {
+			if verdict, err = llm.ParseOutput(request.Output, response.Choices[0].Text); err != nil {
+				return nil, err
+			}
+		}
+		grounding.Judge = verdict.(string)
+		if grounding.Judge != "yes" {
+			grounding.Grounded = false
+		}
+	}
+
+	return &grounding, nil
+}
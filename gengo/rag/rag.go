@@ -0,0 +1,383 @@
+// Package rag answers questions from retrieved chunks and keeps up a
+// conversation with the user.
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/classify"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/split"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
+)
+
+// Fallback is the answer the model is told to give when the context does
+// not contain the answer.
+const Fallback = "Sorry I had trouble answering this question, based on the information I found."
+
+// IsFallback reports whether an answer is essentially only the Fallback,
+// ignoring case, punctuation and a few words around it, as models rarely
+// repeat it exactly.
+func IsFallback(answer string) bool {
+	if !strings.Contains(fallbackWords(answer), fallbackWords(Fallback)) {
+		return false
+	}
+	return len(strings.Fields(fallbackWords(WithoutFallback(answer)))) <= maxFallbackExtra
+}
+
+// maxFallbackExtra is the most words an answer may add to the Fallback and
+// still count as it, like "I'm afraid".
+const maxFallbackExtra = 3
+
+// WithoutFallback returns the answer without the sentences that give the
+// Fallback.
+func WithoutFallback(answer string) string {
+	fallback := fallbackWords(Fallback)
+	kept := []string{}
+	for _, s := range split.SplitSentences(answer) {
+		if !strings.Contains(fallbackWords(s), fallback) {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// fallbackWords lower cases text and keeps only its words, separated by
+// single spaces.
+func fallbackWords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+// Stop tokens.
+var Stop = []string{
+	"#",
+	"import",
+	"Human",
+	"human",
+	"AI:",
+}
+
+// QAPromptTemplate is a template for a question and answer prompt.
+func QAPromptTemplate(context, question string) string {
+	return fmt.Sprintf(`### Instruction:
+Read the context below and answer the question. If the question cannot be answered based on the context alone or the context does not explicitly say the answer to the question, respond "%s"
+
+### Input:
+Context: "%s"
+
+Question: "%s"
+
+### Repsonse:
+`, Fallback, context, question)
+}
+
+// ChatContext is a struct that holds a chat context.
+type ChatContext struct {
+	You string `json:"you"`
+	AI  string `json:"ai"`
+}
+
+// ChatContexts is a slice of chat contexts.
+type ChatContexts []ChatContext
+
+// ChatPromptTemplate is a template for a chat prompt that includes the
+// last three exchanges of the conversation.
+func ChatPromptTemplate(history ChatContexts, input string) string {
+	return fmt.Sprintf(`### Instruction:
+You are a helpful and kind chat assistant. Respond to the below user input based on the following conversation context:
+
+%s
+### Input:
+%s
+
+### Response:
+`, recentHistory(history), input)
+}
+
+// RouterPromptTemplate is a template that asks whether the user is asking
+// an informational question.
+func RouterPromptTemplate(input string) string {
+	return fmt.Sprintf(`### Instruction:
+Is the user asking an informational question or just wanting to chat? Answer "yes" if they are asking an informational question.
+
+### Input:
+%s
+
+### Response:
+`, input)
+}
+
+// recentHistory formats the last three exchanges of the conversation.
+func recentHistory(history ChatContexts) string {
+
+	// Take the last three chat contexts and format them into a string.
+	beg := len(history) - 3
+	if beg < 0 {
+		beg = 0
+	}
+	filteredContextString := ""
+	for _, fc := range history[beg:] {
+		filteredContextString += "Human: " + fc.You + "\nAI: " + fc.AI + "\n\n"
+	}
+	return filteredContextString
+}
+
+// ClarifyPromptTemplate is a template that asks the user to clarify an
+// ambiguous input.
+func ClarifyPromptTemplate(history ChatContexts, input string) string {
+	return fmt.Sprintf(`### Instruction:
+You are a helpful and kind chat assistant. It is not clear whether the below user input is a question about the documentation you know or just conversation. Respond with one short question asking the user what they would like to know, based on the following conversation context:
+
+%s
+### Input:
+%s
+
+### Response:
+`, recentHistory(history), input)
+}
+
+// RouterLabels describe the answers to the router question for a
+// classifier: "yes" for informational questions and "no" for chat.
+var RouterLabels = []classify.Label{
+	{Name: "yes", Description: "an informational question asking for facts or how to do something"},
+	{Name: "no", Description: "casual conversation, greetings or small talk"},
+}
+
+// Answer is a retrieval based answer along with the chunk it was based on,
+// which says where the answer came from.
+type Answer struct {
+	Text       string         `json:"text"`
+	Chunk      document.Chunk `json:"chunk"`
+	Similarity float64        `json:"similarity"`
+
+	// Grounding is set when the assistant checks answers against the
+	// chunk. If the answer was replaced by the Fallback, Grounding is
+	// the check of the original answer.
+	Grounding *Grounding `json:"grounding,omitempty"`
+}
+
+// Assistant answers questions from a set of vectorized chunks and chats
+// with the user otherwise.
+type Assistant struct {
+	Completer llm.Completer
+	Embedder  embedding.Embedder
+	Chunks    vectorstore.VectorizedChunks
+
+	// Model answers questions, ChatModel chats and RouterModel decides
+	// which of the two to do.
+	Model       string
+	ChatModel   string
+	RouterModel string
+
+	// Router, if set, decides whether the input is informational by
+	// scoring RouterLabels instead of prompting RouterModel.
+	Router *classify.Classifier
+
+	// Grounder, if set, checks every answer against the chunk it was
+	// generated from. Ungrounded answers are flagged on the Answer, and
+	// replaced by the Fallback if ReplaceUngrounded is set.
+	Grounder          *Grounder
+	ReplaceUngrounded bool
+
+	// Unsure responds to inputs the Router abstained on. If nil, the
+	// assistant asks a clarifying question.
+	Unsure func(ctx context.Context, input string, history ChatContexts) (string, error)
+}
+
+// NewAssistant returns an Assistant using the workshop models.
+func NewAssistant(completer llm.Completer, embedder embedding.Embedder, chunks vectorstore.VectorizedChunks) *Assistant {
+	return &Assistant{
+		Completer:   completer,
+		Embedder:    embedder,
+		Chunks:      chunks,
+		Model:       "Nous-Hermes-Llama2-13B",
+		ChatModel:   "WizardCoder",
+		RouterModel: "Nous-Hermes-Llama2-13B",
+	}
+}
+
+// AnswerFromContext answers the question from the given passage of text.
+func (a *Assistant) AnswerFromContext(ctx context.Context, passage string, question string) (string, error) {
+
+	// Prompt with the Q&A template.
+	request := llm.CompletionRequest{
+		Prompt: QAPromptTemplate(passage, question),
+		Model:  a.Model,
+	}
+	response, err := a.Completer.Complete(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	return llm.TrimStop(response.Choices[0].Text, Stop), nil
+}
+
+// Answer gets a retrieval based answer.
+func (a *Assistant) Answer(ctx context.Context, question string) (*Answer, error) {
+	if len(a.Chunks) == 0 {
+		return nil, errors.New("rag: no chunks to search")
+	}
+
+	// Embed the question for the RAG answer.
+	vector, err := embedding.EmbedOne(ctx, a.Embedder, question)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search for the relevant chunk.
+	results, err := a.Chunks.Search(vector, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Answer from the chunk.
+	text, err := a.AnswerFromContext(ctx, results[0].Content, question)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := Answer{
+		Text:       text,
+		Chunk:      results[0].Chunk,
+		Similarity: results[0].Similarity,
+	}
+
+	// Check the answer is supported by the chunk.
+	if a.Grounder != nil {
+		answer.Grounding, err = a.Grounder.Check(ctx, answer.Chunk.Content, question, answer.Text)
+		if err != nil {
+			return nil, err
+		}
+		if !answer.Grounding.Grounded && a.ReplaceUngrounded {
+			answer.Text = Fallback
+		}
+	}
+	return &answer, nil
+}
+
+// Chat gets a non-informational chat based answer.
+func (a *Assistant) Chat(ctx context.Context, input string, history ChatContexts) (string, error) {
+	request := llm.CompletionRequest{
+		Prompt: ChatPromptTemplate(history, input),
+		Model:  a.ChatModel,
+	}
+	response, err := a.Completer.Complete(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	return llm.TrimStop(response.Choices[0].Text, Stop), nil
+}
+
+// Route is where Respond sends an input.
+type Route int
+
+// Routes.
+const (
+	RouteChat   Route = iota // chit-chat, answered by the chat model
+	RouteAnswer              // an informational question, answered from the chunks
+	RouteUnsure              // the Router abstained, handled by Unsure
+)
+
+// Route decides whether the input is an informational question, chat, or
+// too unclear to tell. Only a Router with a threshold can be unsure.
+func (a *Assistant) Route(ctx context.Context, input string) (Route, error) {
+	if a.Router != nil {
+		result, err := a.Router.Classify(ctx, input)
+		if err != nil {
+			return RouteChat, err
+		}
+		switch {
+		case result.Abstained:
+			return RouteUnsure, nil
+		case result.Label == "yes":
+			return RouteAnswer, nil
+		}
+		return RouteChat, nil
+	}
+
+	request := llm.CompletionRequest{
+		Prompt: RouterPromptTemplate(input),
+		Model:  a.RouterModel,
+		Output: llm.TypedOutput{
+			Type:       "categorical",
+			Categories: []string{"yes", "no"},
+			Fallback:   "no",
+		},
+	}
+	response, err := a.Completer.Complete(ctx, request)
+	if err != nil {
+		return RouteChat, err
+	}
+
+	// Check the answer here too in case the completer does not.
+	value := response.Choices[0].Value
+	if value == nil {
+		if value, err = llm.ParseOutput(request.Output, response.Choices[0].Text); err != nil {
+			return RouteChat, err
+		}
+	}
+	if value == "yes" {
+		return RouteAnswer, nil
+	}
+	return RouteChat, nil
+}
+
+// IsInformational determines if the user is making an inquiry or just
+// wants to chat. Inputs the Router is unsure about are not informational.
+func (a *Assistant) IsInformational(ctx context.Context, input string) (bool, error) {
+	route, err := a.Route(ctx, input)
+	return route == RouteAnswer, err
+}
+
+// Clarify asks the user what they meant, for inputs that could not be
+// routed with confidence.
+func (a *Assistant) Clarify(ctx context.Context, input string, history ChatContexts) (string, error) {
+	request := llm.CompletionRequest{
+		Prompt: ClarifyPromptTemplate(history, input),
+		Model:  a.ChatModel,
+	}
+	response, err := a.Completer.Complete(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	return llm.TrimStop(response.Choices[0].Text, Stop), nil
+}
+
+// Respond routes the input to a retrieval based answer, to chat, or to
+// the Unsure handler and returns the response.
+func (a *Assistant) Respond(ctx context.Context, input string, history ChatContexts) (string, error) {
+	if len(a.Chunks) == 0 {
+		return a.Chat(ctx, input, history)
+	}
+	route, err := a.Route(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	// Handle the input accordingly.
+	switch route {
+	case RouteUnsure:
+		if a.Unsure != nil {
+			return a.Unsure(ctx, input, history)
+		}
+		return a.Clarify(ctx, input, history)
+	case RouteChat:
+		return a.Chat(ctx, input, history)
+	}
+	answer, err := a.Answer(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return answer.Text, nil
+}
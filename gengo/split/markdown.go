@@ -0,0 +1,187 @@
+package split
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// Markdown splits Markdown recursively: first into sections at headings,
+// then into paragraphs, lists and fenced code blocks, and only blocks too
+// large for a chunk further into list items, lines, sentences and words.
+// The pieces of a section are packed into chunks of up to Size tokens,
+// and each chunk after the first in a section starts with about Overlap
+// tokens from the end of the one before. Chunks never span two sections,
+// and carry the path of headings of their section.
+type Markdown struct {
+	Size    int
+	Overlap int
+
+	// Count counts the tokens in text. If nil, tokens.Count is used.
+	Count func(text string) int
+}
+
+// NewMarkdown returns a Markdown splitter with the default size and
+// overlap.
+func NewMarkdown() *Markdown {
+	return &Markdown{Size: DefaultSize, Overlap: DefaultOverlap}
+}
+
+// Split splits a document's content into chunks. The content of plain
+// text documents is split at paragraphs without looking for Markdown.
+func (m *Markdown) Split(ctx context.Context, doc document.Document) ([]document.Chunk, error) {
+	s := m.sizer()
+	text := doc.Content
+	blocks := parseBlocks(text, doc.Format != document.FormatText)
+
+	// Break the blocks down into pieces that fit in a chunk.
+	pieces := []piece{}
+	for _, b := range blocks {
+		for _, r := range s.fit(text, b.span, b.separators()) {
+			pieces = append(pieces, piece{span: r, section: b.section, headings: b.headings})
+		}
+	}
+	return s.pack(text, pieces), nil
+}
+
+// sizer returns the sizes and counter to split with.
+func (m *Markdown) sizer() sizer {
+	return newSizer(m.Size, m.Overlap, m.Count)
+}
+
+// piece is a span that fits in a chunk, with the section it is in.
+type piece struct {
+	span
+	section  int
+	headings []string
+}
+
+// Kinds of Markdown block.
+const (
+	blockParagraph = iota
+	blockHeading
+	blockList
+	blockCode
+)
+
+// block is a heading, paragraph, list or fenced code block.
+type block struct {
+	span
+	kind     int
+	section  int
+	headings []string
+}
+
+// separators returns the ways a block is split, coarsest first, when it
+// is too large for a chunk.
+func (b block) separators() []separator {
+	switch b.kind {
+	case blockCode:
+		return []separator{lines, words}
+	case blockList:
+		return []separator{items, lines, sentences, words}
+	}
+	return []separator{lines, sentences, words}
+}
+
+var (
+	headingLine = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t#]*$`)
+	fenceLine   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	itemLine    = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+`)
+)
+
+// parseBlocks splits Markdown into blocks, numbering the sections that
+// headings start. Without markdown every run of non-blank lines is a
+// paragraph.
+func parseBlocks(text string, markdown bool) []block {
+	blocks := []block{}
+	section := 0
+	headings := []string{}
+	levels := []int{}
+	var current *block
+	fence := ""
+	end := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+	open := func(kind int, start int) {
+		end()
+		current = &block{span: span{start, start}, kind: kind, section: section, headings: headings}
+	}
+
+	for start := 0; start < len(text); {
+		lineEnd := strings.IndexByte(text[start:], '\n')
+		next := len(text)
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += start
+			next = lineEnd + 1
+		}
+		line := strings.TrimRight(text[start:lineEnd], "\r")
+		blank := strings.TrimSpace(line) == ""
+
+		switch {
+		case fence != "":
+			// Inside a code block, until the closing fence.
+			current.end = start + len(line)
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+				end()
+			}
+		case blank:
+			if current != nil && current.kind == blockList && continuesList(text[next:]) {
+				break
+			}
+			end()
+		case markdown && headingLine.MatchString(line):
+			end()
+			m := headingLine.FindStringSubmatch(line)
+			// Replace the headings at this level and below.
+			level := len(m[1])
+			n := len(levels)
+			for n > 0 && levels[n-1] >= level {
+				n--
+			}
+			levels = append(levels[:n:n], level)
+			headings = append(headings[:n:n], strings.TrimSpace(m[2]))
+			section++
+			current = &block{span: span{start, start + len(line)}, kind: blockHeading, section: section, headings: headings}
+			end()
+		case markdown && fenceLine.MatchString(line):
+			open(blockCode, start)
+			m := fenceLine.FindStringSubmatch(line)
+			fence = m[1][:1] + m[1][:1] + m[1][:1]
+			current.end = start + len(line)
+		case markdown && itemLine.MatchString(line):
+			if current == nil || current.kind != blockList {
+				open(blockList, start)
+			}
+			current.end = start + len(line)
+		default:
+			if current == nil || current.kind == blockCode {
+				open(blockParagraph, start)
+			}
+			current.end = start + len(line)
+		}
+		start = next
+	}
+	end()
+	return blocks
+}
+
+// continuesList reports whether the text after a blank line continues a
+// list: the next non-blank line is an item or indented.
+func continuesList(rest string) bool {
+	for _, line := range strings.Split(rest, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		return itemLine.MatchString(line) || strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package split
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// span is a range of bytes in a document's content.
+type span struct {
+	start int
+	end   int
+}
+
+// sizer fits text into chunks of a size.
+type sizer struct {
+	size    int
+	overlap int
+	count   func(text string) int
+}
+
+// newSizer returns a sizer, using the defaults for a size of zero or less
+// and tokens.Count for a nil count. An overlap that leaves no room for new
+// text is dropped.
+func newSizer(size int, overlap int, count func(text string) int) sizer {
+	s := sizer{size: size, overlap: overlap, count: count}
+	if s.size <= 0 {
+		s.size = DefaultSize
+	}
+	if s.overlap < 0 || s.overlap >= s.size {
+		s.overlap = 0
+	}
+	if s.count == nil {
+		s.count = tokens.Count
+	}
+	return s
+}
+
+// separator splits a span of text into smaller spans, dropping the
+// whitespace between them.
+type separator func(text string, s span) []span
+
+// fit returns the span as it is if it fits in a chunk, and otherwise
+// splits it with the first separator and fits each part with the rest.
+// Spans that are still too large when there are no separators left are
+// returned as they are.
+func (z sizer) fit(text string, s span, separators []separator) []span {
+	if len(separators) == 0 || z.count(text[s.start:s.end]) <= z.size {
+		return []span{s}
+	}
+	parts := separators[0](text, s)
+	if len(parts) <= 1 {
+		return z.fit(text, s, separators[1:])
+	}
+	fitted := []span{}
+	for _, p := range parts {
+		fitted = append(fitted, z.fit(text, p, separators[1:])...)
+	}
+	return fitted
+}
+
+// pack packs the pieces of each section into chunks. A chunk after the
+// first of its section starts up to overlap tokens before its first piece.
+func (z sizer) pack(text string, pieces []piece) []document.Chunk {
+	chunks := []document.Chunk{}
+	sectionStart := 0
+	for i := 0; i < len(pieces); {
+		first := pieces[i]
+		if i == 0 || pieces[i-1].section != first.section {
+			sectionStart = first.start
+		}
+
+		start := first.start
+		if z.overlap > 0 && start > sectionStart {
+			start = z.overlapStart(text, sectionStart, first)
+		}
+		end := first.end
+		j := i + 1
+		for j < len(pieces) && pieces[j].section == first.section && z.count(text[start:pieces[j].end]) <= z.size {
+			end = pieces[j].end
+			j++
+		}
+
+		chunks = append(chunks, document.Chunk{
+			Content:  text[start:end],
+			Headings: first.headings,
+			Start:    start,
+			End:      end,
+		})
+		i = j
+	}
+	return chunks
+}
+
+// overlapStart returns the start of the chunk whose first new piece is
+// first. It is the earliest word after lower such that the text from it to
+// the piece is at most overlap tokens and the chunk up to the end of the
+// piece still fits, preferring words that start a sentence or a line.
+func (z sizer) overlapStart(text string, lower int, first piece) int {
+	best, bestBoundary := first.start, -1
+	for _, w := range wordStarts(text, lower, first.start) {
+		if z.count(text[w:first.start]) > z.overlap || z.count(text[w:first.end]) > z.size {
+			break
+		}
+		best = w
+		if boundary(text, lower, w) {
+			bestBoundary = w
+		}
+	}
+	if bestBoundary >= 0 {
+		return bestBoundary
+	}
+	return best
+}
+
+// boundary reports whether the word at i starts a sentence or a line.
+func boundary(text string, lower int, i int) bool {
+	j := i
+	for j > lower && isSpace(text[j-1]) {
+		if text[j-1] == '\n' {
+			return true
+		}
+		j--
+	}
+	if j == lower {
+		return true
+	}
+	c := text[j-1]
+	return c == '.' || c == '!' || c == '?' || c == ':'
+}
+
+// wordStarts returns the starts of the words between lower and upper,
+// nearest upper first.
+func wordStarts(text string, lower int, upper int) []int {
+	starts := []int{}
+	for i := upper; i > lower; {
+		r, n := utf8.DecodeLastRuneInString(text[:i])
+		prev, _ := utf8.DecodeLastRuneInString(text[:i-n])
+		if !unicode.IsSpace(r) && (i-n == lower || unicode.IsSpace(prev)) {
+			starts = append(starts, i-n)
+		}
+		i -= n
+	}
+	return starts
+}
+
+// lines splits a span into its non-blank lines.
+func lines(text string, s span) []span {
+	parts := []span{}
+	for start := s.start; start < s.end; {
+		end := strings.IndexByte(text[start:s.end], '\n')
+		if end < 0 {
+			end = s.end
+		} else {
+			end += start
+		}
+		if p := trimmed(text, span{start, end}); p.start < p.end {
+			parts = append(parts, p)
+		}
+		start = end + 1
+	}
+	return parts
+}
+
+// items splits a list into its items, each with the lines that follow it
+// up to the next item.
+func items(text string, s span) []span {
+	parts := []span{}
+	for _, l := range lines(text, s) {
+		if itemLine.MatchString(text[l.start:l.end]) || len(parts) == 0 {
+			parts = append(parts, l)
+			continue
+		}
+		parts[len(parts)-1].end = l.end
+	}
+	return parts
+}
+
+// words splits a span at whitespace.
+func words(text string, s span) []span {
+	parts := []span{}
+	start := -1
+	for i := s.start; i <= s.end; i++ {
+		if i == s.end || isSpace(text[i]) {
+			if start >= 0 {
+				parts = append(parts, span{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	return parts
+}
+
+// trimmed returns a span without its leading and trailing whitespace.
+func trimmed(text string, s span) span {
+	for s.start < s.end && isSpace(text[s.start]) {
+		s.start++
+	}
+	for s.end > s.start && isSpace(text[s.end-1]) {
+		s.end--
+	}
+	return s
+}
+
+// isSpace reports whether a byte is ASCII whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -0,0 +1,108 @@
+package split
+
+import (
+	"context"
+	"sort"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// DefaultBreakpoint is the percentile of the distances between adjacent
+// sentences above which a Semantic splitter cuts.
+const DefaultBreakpoint = 0.95
+
+// Semantic cuts a document where the meaning changes. Each sentence is
+// embedded together with its neighbours, and a chunk ends where the cosine
+// distance from one sentence to the next is above the Breakpoint
+// percentile of all those distances in the document, where a Markdown
+// section ends, or where the next sentence would make it larger than Size
+// tokens.
+type Semantic struct {
+	Embedder   embedding.Embedder
+	Size       int
+	Breakpoint float64
+
+	// Level is how many headings from the top of two sections' heading
+	// paths must match for them to share a chunk, so with a Level of 1
+	// sibling subsections under the same top heading can. Zero compares
+	// the whole paths, keeping every section apart.
+	Level int
+
+	// Count counts the tokens in text. If nil, tokens.Count is used.
+	Count func(text string) int
+}
+
+// Split implements Splitter.
+func (s *Semantic) Split(ctx context.Context, doc document.Document) ([]document.Chunk, error) {
+	z := newSizer(s.Size, 0, s.Count)
+	text := doc.Content
+	us := units(doc, z)
+	if len(us) <= 1 {
+		return chunks(doc, us), nil
+	}
+
+	// Embed each sentence with the ones either side of it, which makes the
+	// distances less noisy than embedding sentences alone.
+	windows := make([]string, len(us))
+	for i := range us {
+		first, last := max(i-1, 0), min(i+1, len(us)-1)
+		windows[i] = text[us[first].start:us[last].end]
+	}
+	vectors, err := embedding.EmbedAll(ctx, s.Embedder, windows, embedding.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	distances := make([]float64, len(us)-1)
+	for i := range distances {
+		similarity, err := embedding.CosineSimilarity(vectors[i], vectors[i+1])
+		if err != nil {
+			return nil, err
+		}
+		distances[i] = 1 - similarity
+	}
+	breakpoint := s.Breakpoint
+	if breakpoint <= 0 || breakpoint > 1 {
+		breakpoint = DefaultBreakpoint
+	}
+	threshold := percentile(distances, breakpoint)
+
+	headings := headingIndex(doc)
+	spans := []span{}
+	first := 0
+	for i := 1; i <= len(us); i++ {
+		if i < len(us) &&
+			distances[i-1] <= threshold &&
+			sameSection(headings(us[i-1].start), headings(us[i].start), s.Level) &&
+			z.count(text[us[first].start:us[i].end]) <= z.size {
+			continue
+		}
+		spans = append(spans, span{us[first].start, us[i-1].end})
+		first = i
+	}
+	return chunks(doc, spans), nil
+}
+
+// percentile returns the value below which the fraction p of values fall.
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// sameSection reports whether the first level headings of two heading
+// paths are the same, or the whole paths if the level is zero.
+func sameSection(a []string, b []string, level int) bool {
+	if level > 0 {
+		a, b = a[:min(len(a), level)], b[:min(len(b), level)]
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
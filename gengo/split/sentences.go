@@ -0,0 +1,129 @@
+package split
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+)
+
+// Sentences packs whole sentences into chunks of up to Size tokens. Each
+// chunk after the first starts with the last sentences of the one before
+// that fit in Overlap tokens and leave room for a new sentence. Fenced
+// code blocks are kept whole where they fit, and sentences too large for a
+// chunk are split into lines and then words.
+type Sentences struct {
+	Size    int
+	Overlap int
+
+	// Count counts the tokens in text. If nil, tokens.Count is used.
+	Count func(text string) int
+}
+
+// Split implements Splitter.
+func (s *Sentences) Split(ctx context.Context, doc document.Document) ([]document.Chunk, error) {
+	z := newSizer(s.Size, s.Overlap, s.Count)
+	text := doc.Content
+	us := units(doc, z)
+
+	spans := []span{}
+	for i := 0; i < len(us); {
+		// Start with the sentences of the last chunk that fit in the
+		// overlap and leave room for at least one new sentence.
+		first := i
+		for first > 0 && len(spans) > 0 && us[first-1].start > spans[len(spans)-1].start &&
+			z.count(text[us[first-1].start:us[i-1].end]) <= z.overlap &&
+			z.count(text[us[first-1].start:us[i].end]) <= z.size {
+			first--
+		}
+		j := i + 1
+		for j < len(us) && z.count(text[us[first].start:us[j].end]) <= z.size {
+			j++
+		}
+		spans = append(spans, span{us[first].start, us[j-1].end})
+		i = j
+	}
+	return chunks(doc, spans), nil
+}
+
+// abbreviations are words that end in a period without ending a sentence.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sr": true, "jr": true,
+	"st": true, "mt": true, "vs": true, "etc": true, "cf": true, "al": true, "approx": true,
+	"fig": true, "figs": true, "no": true, "nos": true, "vol": true, "ed": true, "eds": true,
+	"p": true, "pp": true, "ch": true, "sec": true, "dept": true, "est": true, "inc": true,
+	"ltd": true, "co": true, "corp": true, "jan": true, "feb": true, "mar": true, "apr": true,
+	"jun": true, "jul": true, "aug": true, "sep": true, "sept": true, "oct": true, "nov": true,
+	"dec": true, "e.g": true, "i.e": true, "a.m": true, "p.m": true, "u.s": true, "u.k": true,
+}
+
+// SplitSentences splits text into sentences. It knows common
+// abbreviations and initials, so "Dr. Smith arrived at 5 p.m. on Monday."
+// is one sentence.
+func SplitSentences(text string) []string {
+	out := []string{}
+	for _, b := range parseBlocks(text, false) {
+		for _, s := range sentences(text, b.span) {
+			out = append(out, text[s.start:s.end])
+		}
+	}
+	return out
+}
+
+// sentences splits a span into sentences. A sentence ends at ., ! or ?,
+// and any closing quotes or brackets, followed by whitespace, unless the
+// period ends an abbreviation or an initial or the next word starts in
+// lower case.
+func sentences(text string, s span) []span {
+	parts := []span{}
+	start := s.start
+	add := func(end int) {
+		if p := trimmed(text, span{start, end}); p.start < p.end {
+			parts = append(parts, p)
+		}
+		start = end
+	}
+	for i := s.start; i < s.end; i++ {
+		c := text[i]
+		if c != '.' && c != '!' && c != '?' {
+			continue
+		}
+		end := i + 1
+		for end < s.end {
+			r, n := utf8.DecodeRuneInString(text[end:s.end])
+			if !strings.ContainsRune(`"')]’”»`, r) {
+				break
+			}
+			end += n
+		}
+		if end < s.end && !isSpace(text[end]) {
+			continue
+		}
+		if c == '.' && abbreviation(text, start, i) {
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(strings.TrimLeft(text[end:s.end], " \t\r\n")); unicode.IsLower(next) {
+			continue
+		}
+		add(end)
+		i = end - 1
+	}
+	add(s.end)
+	return parts
+}
+
+// abbreviation reports whether the period at dot ends an abbreviation or
+// an initial.
+func abbreviation(text string, lower int, dot int) bool {
+	k := dot
+	for k > lower && !isSpace(text[k-1]) {
+		k--
+	}
+	word := strings.ToLower(strings.TrimLeft(text[k:dot], `"'([`))
+	if r, n := utf8.DecodeRuneInString(word); n == len(word) && unicode.IsLetter(r) {
+		return true
+	}
+	return abbreviations[word] || strings.Contains(word, ".")
+}
@@ -0,0 +1,140 @@
+// Package split splits documents into chunks to embed, sized in model
+// tokens rather than words. Each way of splitting is a Splitter, and New
+// picks one from a Config:
+//
+//   - Markdown splits at headings, then blocks, then smaller units.
+//   - Window cuts fixed windows of tokens.
+//   - Sentences packs whole sentences.
+//   - Semantic cuts where the meaning of adjacent sentences changes.
+package split
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// Default chunking parameters, in tokens.
+const (
+	DefaultSize    = 256
+	DefaultOverlap = 32
+)
+
+// Splitter splits documents into chunks.
+type Splitter interface {
+	Split(ctx context.Context, doc document.Document) ([]document.Chunk, error)
+}
+
+// Strategies that New can build.
+const (
+	StrategyMarkdown  = "markdown"
+	StrategyWindow    = "window"
+	StrategySentences = "sentences"
+	StrategySemantic  = "semantic"
+)
+
+// Config selects and sizes a splitter.
+type Config struct {
+	// Strategy is one of the Strategy constants. If empty, Markdown is
+	// used.
+	Strategy string `yaml:"strategy" json:"strategy"`
+
+	// Size and Overlap are in tokens. Zero uses the defaults, and a
+	// negative overlap means none. Semantic splitting does not overlap.
+	Size    int `yaml:"size" json:"size"`
+	Overlap int `yaml:"overlap" json:"overlap"`
+
+	// Breakpoint is the percentile of the distances between adjacent
+	// sentences above which semantic splitting cuts. Zero uses
+	// DefaultBreakpoint.
+	Breakpoint float64 `yaml:"breakpoint" json:"breakpoint"`
+}
+
+// New returns the splitter the config selects. The embedder is only used,
+// and then required, by semantic splitting.
+func New(cfg Config, e embedding.Embedder) (Splitter, error) {
+	size, overlap := cfg.Size, cfg.Overlap
+	if size == 0 {
+		size = DefaultSize
+	}
+	if overlap == 0 {
+		overlap = DefaultOverlap
+	}
+	switch cfg.Strategy {
+	case "", StrategyMarkdown:
+		return &Markdown{Size: size, Overlap: overlap}, nil
+	case StrategyWindow:
+		return &Window{Size: size, Overlap: overlap}, nil
+	case StrategySentences:
+		return &Sentences{Size: size, Overlap: overlap}, nil
+	case StrategySemantic:
+		if e == nil {
+			return nil, fmt.Errorf("split: %s splitting needs an embedder", cfg.Strategy)
+		}
+		return &Semantic{Embedder: e, Size: size, Breakpoint: cfg.Breakpoint}, nil
+	}
+	return nil, fmt.Errorf("split: unknown strategy %q", cfg.Strategy)
+}
+
+// headingIndex returns a function that gives the path of headings of the
+// Markdown section an offset of a document is in, or nil for plain text.
+func headingIndex(doc document.Document) func(offset int) []string {
+	if doc.Format == document.FormatText {
+		return func(int) []string { return nil }
+	}
+	blocks := parseBlocks(doc.Content, true)
+	return func(offset int) []string {
+		i := sort.Search(len(blocks), func(i int) bool { return blocks[i].start > offset })
+		if i == 0 {
+			return nil
+		}
+		return blocks[i-1].headings
+	}
+}
+
+// chunks returns the chunks of a document's content in the spans, trimmed
+// of surrounding whitespace. Spans that trimming leaves empty or within
+// the chunk before are dropped.
+func chunks(doc document.Document, spans []span) []document.Chunk {
+	headings := headingIndex(doc)
+	out := []document.Chunk{}
+	for _, s := range spans {
+		s = trimmed(doc.Content, s)
+		if s.start == s.end || len(out) > 0 && s.end <= out[len(out)-1].End {
+			continue
+		}
+		out = append(out, document.Chunk{
+			Content:  doc.Content[s.start:s.end],
+			Headings: headings(s.start),
+			Start:    s.start,
+			End:      s.end,
+		})
+	}
+	return out
+}
+
+// units splits a document into sentences and list items, keeping fenced
+// code blocks whole, and breaks up any unit larger than the sizer's size.
+func units(doc document.Document, z sizer) []span {
+	text := doc.Content
+	out := []span{}
+	for _, b := range parseBlocks(text, doc.Format != document.FormatText) {
+		parts := []span{b.span}
+		switch b.kind {
+		case blockList:
+			parts = []span{}
+			for _, item := range items(text, b.span) {
+				parts = append(parts, sentences(text, item)...)
+			}
+		case blockParagraph, blockHeading:
+			parts = sentences(text, b.span)
+		}
+		for _, p := range parts {
+			out = append(out, z.fit(text, p, []separator{lines, words})...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,438 @@
+package split
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// countWords counts whitespace separated words, so that sizes in tests do
+// not depend on the tokenizer.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// testDocument returns a Markdown document with sections, lists, code and
+// paragraphs of short sentences.
+func testDocument() document.Document {
+	var b strings.Builder
+	b.WriteString("# Guide\n\nThis guide covers setup. It is short. Read it first.\n\n")
+	for s := 1; s <= 3; s++ {
+		fmt.Fprintf(&b, "## Part %d\n\n", s)
+		for p := 0; p < 4; p++ {
+			for i := 0; i < 6; i++ {
+				fmt.Fprintf(&b, "Sentence %d of paragraph %d in part %d is here. ", i, p, s)
+			}
+			b.WriteString("\n\n")
+		}
+		b.WriteString("- first item of the list\n- second item, e.g. this one\n- third item\n\n")
+		b.WriteString("```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n")
+	}
+	return document.Document{Source: "test.md", Format: document.FormatMarkdown, Content: b.String()}
+}
+
+// testProse returns a plain text document of one long paragraph.
+func testProse() document.Document {
+	var b strings.Builder
+	for i := 0; i < 80; i++ {
+		fmt.Fprintf(&b, "Sentence number %d talks about topic %d. ", i, i%7)
+	}
+	return document.Document{Source: "prose.txt", Format: document.FormatText, Content: strings.TrimSpace(b.String())}
+}
+
+// fakeEmbedder embeds texts by the topics they mention, so sentences
+// about different topics are far apart.
+var fakeEmbedder = embedding.EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		v := make([]float64, 8)
+		v[7] = 0.1
+		for t := 0; t < 7; t++ {
+			v[t] = float64(strings.Count(text, fmt.Sprintf("part %d", t)))
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+})
+
+// checkOffsets checks that every chunk is the text at its offsets, and
+// that the chunks together cover all of the text but whitespace.
+func checkOffsets(t *testing.T, doc document.Document, chunks []document.Chunk) {
+	t.Helper()
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	covered := make([]bool, len(doc.Content))
+	for i, c := range chunks {
+		if c.Start < 0 || c.End > len(doc.Content) || c.Start >= c.End {
+			t.Fatalf("chunk %d has offsets %d-%d", i, c.Start, c.End)
+		}
+		if got := doc.Content[c.Start:c.End]; got != c.Content {
+			t.Fatalf("chunk %d is %q, but the text at its offsets is %q", i, c.Content, got)
+		}
+		if i > 0 && c.Start < chunks[i-1].Start {
+			t.Errorf("chunk %d starts at %d, before chunk %d at %d", i, c.Start, i-1, chunks[i-1].Start)
+		}
+		for j := c.Start; j < c.End; j++ {
+			covered[j] = true
+		}
+	}
+	for j, ok := range covered {
+		if !ok && !isSpace(doc.Content[j]) {
+			t.Fatalf("text at %d is in no chunk: %q", j, doc.Content[j:min(j+40, len(doc.Content))])
+		}
+	}
+}
+
+// checkOverlap checks that consecutive chunks in the same section overlap
+// by some text, but by no more than the overlap.
+func checkOverlap(t *testing.T, doc document.Document, chunks []document.Chunk, overlap int) {
+	t.Helper()
+	overlapping := 0
+	for i := 1; i < len(chunks); i++ {
+		prev, c := chunks[i-1], chunks[i]
+		if !sameSection(prev.Headings, c.Headings, 0) {
+			continue
+		}
+		if c.Start >= prev.End {
+			t.Errorf("chunk %d starts at %d, after the end of chunk %d at %d", i, c.Start, i-1, prev.End)
+			continue
+		}
+		if n := countWords(doc.Content[c.Start:prev.End]); n > overlap {
+			t.Errorf("chunks %d and %d overlap by %d tokens, more than %d", i-1, i, n, overlap)
+		}
+		overlapping++
+	}
+	if overlapping == 0 {
+		t.Error("no chunks overlap")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	doc := testDocument()
+	m := &Markdown{Size: 40, Overlap: 8, Count: countWords}
+	chunks, err := m.Split(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, doc, chunks)
+	checkOverlap(t, doc, chunks, m.Overlap)
+	for i, c := range chunks {
+		if n := countWords(c.Content); n > m.Size {
+			t.Errorf("chunk %d has %d tokens, more than %d", i, n, m.Size)
+		}
+	}
+}
+
+func TestSentences(t *testing.T) {
+	for _, doc := range []document.Document{testDocument(), testProse()} {
+		s := &Sentences{Size: 40, Overlap: 12, Count: countWords}
+		chunks, err := s.Split(context.Background(), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkOffsets(t, doc, chunks)
+		checkOverlap(t, doc, chunks, s.Overlap)
+		for i, c := range chunks {
+			if n := countWords(c.Content); n > s.Size {
+				t.Errorf("%s: chunk %d has %d tokens, more than %d", doc.Source, i, n, s.Size)
+			}
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	doc := testProse()
+	w := &Window{Size: 30, Overlap: 6}
+	chunks, err := w.Split(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, doc, chunks)
+
+	// Every pair of consecutive windows shares exactly Overlap tokens.
+	offsets := tokens.Boundaries(doc.Content)
+	shared := func(a, b document.Chunk) int {
+		n := 0
+		for i, start := range offsets {
+			end := len(doc.Content)
+			if i+1 < len(offsets) {
+				end = offsets[i+1]
+			}
+			if start < a.End && end > a.Start && start < b.End && end > b.Start {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 1; i < len(chunks); i++ {
+		if n := shared(chunks[i-1], chunks[i]); n != w.Overlap {
+			t.Errorf("chunks %d and %d share %d tokens, want %d", i-1, i, n, w.Overlap)
+		}
+	}
+}
+
+func TestSemantic(t *testing.T) {
+	doc := testDocument()
+	s := &Semantic{Embedder: fakeEmbedder, Size: 40, Count: countWords}
+	chunks, err := s.Split(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, doc, chunks)
+
+	// Semantic chunks do not overlap, and never span two sections.
+	for i, c := range chunks {
+		if i > 0 && c.Start < chunks[i-1].End {
+			t.Errorf("chunks %d and %d overlap", i-1, i)
+		}
+		if n := countWords(c.Content); n > s.Size {
+			t.Errorf("chunk %d has %d tokens, more than %d", i, n, s.Size)
+		}
+		if strings.Contains(c.Content, "\n#") {
+			t.Errorf("chunk %d spans two sections: %q", i, c.Content)
+		}
+	}
+}
+
+func TestSemanticLevel(t *testing.T) {
+	headings := [][]string{{"Guide", "Part 1"}, {"Guide", "Part 2"}}
+	if sameSection(headings[0], headings[1], 0) {
+		t.Error("sibling sections are the same section with no level")
+	}
+	if !sameSection(headings[0], headings[1], 1) {
+		t.Error("sibling sections are different sections at level 1")
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	got := SplitSentences(`Dr. Smith arrived at 5 p.m. on Monday. He said "hi." Then J. R. R. Tolkien wrote, e.g. books. Was it good? Yes!`)
+	want := []string{
+		"Dr. Smith arrived at 5 p.m. on Monday.",
+		`He said "hi."`,
+		"Then J. R. R. Tolkien wrote, e.g. books.",
+		"Was it good?",
+		"Yes!",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWindowOverlapNeverStalls(t *testing.T) {
+	doc := testProse()
+	chunks, err := (&Window{Size: 4, Overlap: 10}).Split(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) == 0 || len(chunks) > len(tokens.Boundaries(doc.Content)) {
+		t.Errorf("got %d chunks", len(chunks))
+	}
+}
+
+// randomWords are the words random documents are made of, including
+// abbreviations and a topic word for the fake embedder.
+var randomWords = []string{
+	"the", "gopher", "compiles", "a", "program", "quickly", "and", "tests", "it",
+	"Dr.", "e.g.", "p.m.", "J.", "part 1", "part 2", "part 3", "channel", "goroutine",
+	"interface", "with", "`code`", "**bold**", "supercalifragilisticexpialidocious",
+}
+
+// randomSentence returns a sentence of up to n words, sometimes without
+// the punctuation that ends it.
+func randomSentence(r *rand.Rand, n int) string {
+	words := make([]string, 1+r.Intn(n))
+	for i := range words {
+		words[i] = randomWords[r.Intn(len(randomWords))]
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ") + []string{".", "!", "?", "", `."`}[r.Intn(5)]
+}
+
+// randomDocument returns a Markdown or plain text document of random
+// headings, paragraphs, lists and code blocks, with sentences from short
+// to longer than any chunk.
+func randomDocument(r *rand.Rand) document.Document {
+	var b strings.Builder
+	for i := r.Intn(12); i >= 0; i-- {
+		switch r.Intn(6) {
+		case 0:
+			fmt.Fprintf(&b, "%s %s\n\n", strings.Repeat("#", 1+r.Intn(3)), randomSentence(r, 4))
+		case 1:
+			for j := r.Intn(5); j >= 0; j-- {
+				fmt.Fprintf(&b, "- %s\n", randomSentence(r, 12))
+			}
+			b.WriteString("\n")
+		case 2:
+			b.WriteString("```\n")
+			for j := r.Intn(8); j >= 0; j-- {
+				fmt.Fprintf(&b, "%s\n", randomSentence(r, 8))
+			}
+			b.WriteString("```\n\n")
+		default:
+			for j := r.Intn(8); j >= 0; j-- {
+				b.WriteString(randomSentence(r, []int{6, 20, 80}[r.Intn(3)]) + " ")
+			}
+			b.WriteString("\n\n")
+		}
+	}
+	format := document.FormatMarkdown
+	if r.Intn(4) == 0 {
+		format = document.FormatText
+	}
+	return document.Document{Source: "random.md", Format: format, Content: b.String()}
+}
+
+// checkCovered checks that every chunk is the text at its offsets, that
+// each chunk ends with text the one before did not have, and that together
+// they cover all of the text but whitespace, for documents of any number
+// of chunks.
+func checkCovered(t *testing.T, doc document.Document, chunks []document.Chunk) {
+	t.Helper()
+	covered := make([]bool, len(doc.Content))
+	for i, c := range chunks {
+		if c.Start < 0 || c.End > len(doc.Content) || c.Start >= c.End || doc.Content[c.Start:c.End] != c.Content {
+			t.Fatalf("chunk %d at %d-%d is not the text at its offsets: %q", i, c.Start, c.End, c.Content)
+		}
+		if prev := chunks[max(i-1, 0)]; i > 0 && (c.Start < prev.Start || c.End <= prev.End) {
+			t.Fatalf("chunk %d at %d-%d adds nothing after chunk %d at %d-%d", i, c.Start, c.End, i-1, prev.Start, prev.End)
+		}
+		for j := c.Start; j < c.End; j++ {
+			covered[j] = true
+		}
+	}
+	for j, ok := range covered {
+		if !ok && !isSpace(doc.Content[j]) {
+			t.Fatalf("text at %d is in no chunk: %q", j, doc.Content[j:min(j+40, len(doc.Content))])
+		}
+	}
+}
+
+// checkSizes checks that no chunk is larger than size and no two
+// consecutive chunks share more than overlap tokens, as counted in the
+// text between two offsets by count. It returns the number of consecutive
+// chunks that overlap.
+func checkSizes(t *testing.T, chunks []document.Chunk, size int, overlap int, count func(start, end int) int) int {
+	t.Helper()
+	overlapping := 0
+	for i, c := range chunks {
+		if n := count(c.Start, c.End); n > size {
+			t.Errorf("chunk %d has %d tokens, more than %d: %q", i, n, size, c.Content)
+		}
+		if i > 0 && c.Start < chunks[i-1].End {
+			if n := count(c.Start, chunks[i-1].End); n > overlap {
+				t.Errorf("chunks %d and %d overlap by %d tokens, more than %d", i-1, i, n, overlap)
+			}
+			overlapping++
+		}
+	}
+	return overlapping
+}
+
+// wordCounter counts the words in the text between two offsets of a
+// document.
+func wordCounter(doc document.Document) func(start, end int) int {
+	return func(start, end int) int {
+		return countWords(doc.Content[start:end])
+	}
+}
+
+// randomSizes returns a chunk size and an overlap smaller than it.
+func randomSizes(r *rand.Rand) (int, int) {
+	size := 3 + r.Intn(60)
+	return size, r.Intn(size)
+}
+
+func TestRandomMarkdown(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	overlapping := 0
+	for i := 0; i < 500; i++ {
+		doc := randomDocument(r)
+		size, overlap := randomSizes(r)
+		chunks, err := (&Markdown{Size: size, Overlap: overlap, Count: countWords}).Split(context.Background(), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCovered(t, doc, chunks)
+		overlapping += checkSizes(t, chunks, size, overlap, wordCounter(doc))
+		if t.Failed() {
+			t.Fatalf("document %d, size %d, overlap %d:\n%s", i, size, overlap, doc.Content)
+		}
+	}
+	if overlapping == 0 {
+		t.Error("no chunks overlap")
+	}
+}
+
+func TestRandomSentences(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	overlapping := 0
+	for i := 0; i < 500; i++ {
+		doc := randomDocument(r)
+		size, overlap := randomSizes(r)
+		chunks, err := (&Sentences{Size: size, Overlap: overlap, Count: countWords}).Split(context.Background(), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCovered(t, doc, chunks)
+		overlapping += checkSizes(t, chunks, size, overlap, wordCounter(doc))
+		if t.Failed() {
+			t.Fatalf("document %d, size %d, overlap %d:\n%s", i, size, overlap, doc.Content)
+		}
+	}
+	if overlapping == 0 {
+		t.Error("no chunks overlap")
+	}
+}
+
+func TestRandomWindow(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	overlapping := 0
+	for i := 0; i < 500; i++ {
+		doc := randomDocument(r)
+		size, overlap := randomSizes(r)
+		chunks, err := (&Window{Size: size, Overlap: overlap}).Split(context.Background(), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCovered(t, doc, chunks)
+
+		// Windows are measured in the document's tokens.
+		offsets := tokens.Boundaries(doc.Content)
+		count := func(start, end int) int {
+			first := max(sort.SearchInts(offsets, start+1)-1, 0)
+			return sort.SearchInts(offsets, end) - first
+		}
+		overlapping += checkSizes(t, chunks, size, overlap, count)
+		if t.Failed() {
+			t.Fatalf("document %d, size %d, overlap %d:\n%s", i, size, overlap, doc.Content)
+		}
+	}
+	if overlapping == 0 {
+		t.Error("no chunks overlap")
+	}
+}
+
+func TestRandomSemantic(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 500; i++ {
+		doc := randomDocument(r)
+		size := 3 + r.Intn(60)
+		chunks, err := (&Semantic{Embedder: fakeEmbedder, Size: size, Count: countWords}).Split(context.Background(), doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCovered(t, doc, chunks)
+		checkSizes(t, chunks, size, 0, wordCounter(doc))
+		if t.Failed() {
+			t.Fatalf("document %d, size %d:\n%s", i, size, doc.Content)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package split
+
+import (
+	"context"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// Window cuts a document into windows of Size tokens, each starting
+// Overlap tokens before the end of the one before, whatever the text's
+// structure.
+type Window struct {
+	Size    int
+	Overlap int
+}
+
+// Split implements Splitter.
+func (w *Window) Split(ctx context.Context, doc document.Document) ([]document.Chunk, error) {
+	z := newSizer(w.Size, w.Overlap, nil)
+	offsets := tokens.Boundaries(doc.Content)
+	step := z.size - z.overlap
+
+	spans := []span{}
+	for i := 0; i < len(offsets); i += step {
+		end := min(i+z.size, len(offsets))
+		s := span{offsets[i], len(doc.Content)}
+		if end < len(offsets) {
+			s.end = offsets[end]
+		}
+		spans = append(spans, s)
+		if end == len(offsets) {
+			break
+		}
+	}
+	return chunks(doc, spans), nil
+}
@@ -0,0 +1,286 @@
+package structured
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema that can be derived from a Go type
+// and checked by Validate.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+
+	// order keeps the properties in struct field order for validation
+	// messages.
+	order []string
+}
+
+// timeType is time.Time, which marshals to a string.
+var timeType = reflect.TypeOf(time.Time{})
+
+// quotedPatterns match the numbers that fields with the ",string" json
+// option are quoted as.
+var quotedPatterns = map[string]string{
+	"integer": `^-?[0-9]+$`,
+	"number":  `^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`,
+}
+
+// SchemaFor derives the schema of T. Struct fields are named by their json
+// tag and can be annotated with:
+//
+//	required:"true"           the field must be present
+//	enum:"POS,NEU,NEG"        the value must be one of the listed strings
+//	pattern:"^[0-9]{5}$"      the value must match the regular expression
+//	description:"..."         a hint for the model
+//
+// Embedded structs are flattened as encoding/json does, []byte is a base64
+// string, and fields with the ",string" option are strings holding their
+// value. A struct that contains itself, directly or not, is left
+// unconstrained where it recurs.
+func SchemaFor[T any]() (*Schema, error) {
+	var zero T
+	return schemaOf(reflect.TypeOf(&zero).Elem(), map[reflect.Type]bool{})
+}
+
+// schemaOf derives the schema of t. Structs whose schemas are being derived
+// are in progress, and are left unconstrained if they recur.
+func schemaOf(t reflect.Type, progress map[reflect.Type]bool) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := schemaOf(t.Elem(), progress)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("structured: map key of %s must be a string", t)
+		}
+		return &Schema{Type: "object"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Struct:
+		if progress[t] {
+			return &Schema{}, nil
+		}
+		progress[t] = true
+		defer delete(progress, t)
+		return structSchema(t, progress)
+	}
+	return nil, fmt.Errorf("structured: unsupported type %s", t)
+}
+
+// structSchema derives the schema of a struct from the fields
+// encoding/json marshals.
+func structSchema(t reflect.Type, progress map[reflect.Type]bool) (*Schema, error) {
+	closed := false
+	s := Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: &closed,
+	}
+
+	for _, jf := range jsonFields(t) {
+		name, f := jf.name, jf.StructField
+		prop, err := schemaOf(f.Type, progress)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		if jf.quoted {
+			prop = quoted(prop)
+		}
+		prop.Description = f.Tag.Get("description")
+
+		// Enums and patterns constrain strings, or the items of a list of
+		// strings.
+		target := prop
+		if prop.Type == "array" && prop.Items != nil {
+			target = prop.Items
+		}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			for _, v := range strings.Split(enum, ",") {
+				target.Enum = append(target.Enum, strings.TrimSpace(v))
+			}
+		}
+		if pattern := f.Tag.Get("pattern"); pattern != "" {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+			target.Pattern = pattern
+		}
+
+		s.Properties[name] = prop
+		s.order = append(s.order, name)
+		if f.Tag.Get("required") == "true" {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return &s, nil
+}
+
+// jsonField is a field as encoding/json sees it.
+type jsonField struct {
+	reflect.StructField
+	name   string
+	depth  int
+	tagged bool
+
+	// quoted is set by the ",string" option on a field encoding/json
+	// quotes: a string, number or bool.
+	quoted bool
+
+	// order is the position of the field among all those found.
+	order int
+}
+
+// jsonFields returns the fields of a struct that encoding/json marshals, in
+// order. The fields of embedded structs without a json name are promoted,
+// and a promoted field is hidden by a shallower field of the same name, or
+// by a tagged one at the same depth. Fields of the same name that neither
+// hides are all dropped.
+func jsonFields(t reflect.Type) []jsonField {
+	all := []jsonField{}
+	seen := map[reflect.Type]bool{t: true}
+	var walk func(t reflect.Type, depth int)
+	walk = func(t reflect.Type, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if f.Anonymous {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if name == "" && ft.Kind() == reflect.Struct {
+					if !seen[ft] {
+						seen[ft] = true
+						walk(ft, depth+1)
+					}
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			field := jsonField{StructField: f, name: name, depth: depth, tagged: name != "", order: len(all)}
+			field.quoted = hasOption(options, "string") && quotable(f.Type)
+			if name == "" {
+				field.name = f.Name
+			}
+			all = append(all, field)
+		}
+	}
+	walk(t, 0)
+
+	// Keep the dominant field of each name.
+	byName := map[string][]jsonField{}
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fields := []jsonField{}
+	for _, f := range all {
+		if dominant, ok := dominantField(byName[f.name]); ok && dominant.order == f.order {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// dominantField returns the field of a name that encoding/json uses: the
+// only shallowest one, or the only tagged one among the shallowest.
+func dominantField(fields []jsonField) (jsonField, bool) {
+	shallowest := []jsonField{}
+	for _, f := range fields {
+		switch {
+		case len(shallowest) == 0 || f.depth < shallowest[0].depth:
+			shallowest = []jsonField{f}
+		case f.depth == shallowest[0].depth:
+			shallowest = append(shallowest, f)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	tagged := []jsonField{}
+	for _, f := range shallowest {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return jsonField{}, false
+}
+
+// hasOption reports whether the comma separated json tag options include
+// option.
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// quotable reports whether encoding/json applies the ",string" option to a
+// field of type t.
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// quoted returns the schema of a value encoded as a JSON string: numbers
+// and bools become strings that hold them, and strings stay strings.
+func quoted(s *Schema) *Schema {
+	switch s.Type {
+	case "boolean":
+		return &Schema{Type: "string", Enum: []string{"true", "false"}}
+	case "integer", "number":
+		return &Schema{Type: "string", Pattern: quotedPatterns[s.Type]}
+	}
+	return s
+}
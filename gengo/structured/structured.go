@@ -0,0 +1,146 @@
+// Package structured asks a model for JSON that conforms to a schema
+// derived from a Go struct and decodes the answer into that struct.
+//
+//	type Review struct {
+//		Sentiment string   `json:"sentiment" required:"true" enum:"POS,NEU,NEG"`
+//		Topics    []string `json:"topics" description:"what the review talks about"`
+//		Zip       string   `json:"zip,omitempty" pattern:"^[0-9]{5}$"`
+//	}
+//
+//	review, err := structured.Complete[Review](ctx, client, request, nil)
+package structured
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// DefaultMaxAttempts is the number of times Complete asks the model before
+// giving up.
+const DefaultMaxAttempts = 3
+
+// Options configure Complete. A nil *Options uses the defaults.
+type Options struct {
+	// MaxAttempts is the number of requests made, including the first,
+	// before returning a ValidationError.
+	MaxAttempts int
+}
+
+// ValidationError is returned when the model did not produce conforming
+// output within the allowed attempts.
+type ValidationError struct {
+	Output   string
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return "structured: output does not match the schema: " + strings.Join(e.Problems, "; ")
+}
+
+// Complete asks the model for a JSON value matching the schema of T. If the
+// answer does not parse or validate, the model is asked again with the
+// problems listed, up to MaxAttempts times.
+func Complete[T any](ctx context.Context, c llm.Completer, request llm.CompletionRequest, opts *Options) (T, error) {
+	var zero T
+
+	schema, err := SchemaFor[T]()
+	if err != nil {
+		return zero, err
+	}
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return zero, err
+	}
+
+	attempts := DefaultMaxAttempts
+	if opts != nil && opts.MaxAttempts > 0 {
+		attempts = opts.MaxAttempts
+	}
+
+	// Ask for JSON, then keep asking with the problems listed until the
+	// output conforms or we run out of attempts.
+	base := request.Prompt
+	request.Prompt = SchemaPrompt(base, schemaJSON)
+	var lastErr *ValidationError
+	for i := 0; i < attempts; i++ {
+		response, err := c.Complete(ctx, request)
+		if err != nil {
+			return zero, err
+		}
+		output := response.Choices[0].Text
+
+		value, problems := decode[T](schema, output)
+		if len(problems) == 0 {
+			return value, nil
+		}
+		lastErr = &ValidationError{Output: output, Problems: problems}
+		request.Prompt = RetryPrompt(base, schemaJSON, output, problems)
+	}
+	return zero, lastErr
+}
+
+// SchemaPrompt adds the instruction to answer with conforming JSON to a
+// prompt.
+func SchemaPrompt(prompt string, schema []byte) string {
+	return fmt.Sprintf(`%s
+
+Respond only with a JSON value that conforms to this JSON Schema, with no other text:
+%s
+
+JSON:
+`, strings.TrimRight(prompt, "\n"), schema)
+}
+
+// RetryPrompt asks again after an answer that did not conform, listing the
+// problems with it.
+func RetryPrompt(prompt string, schema []byte, output string, problems []string) string {
+	return fmt.Sprintf(`%s
+
+Respond only with a JSON value that conforms to this JSON Schema, with no other text:
+%s
+
+Your previous response was:
+%s
+
+It was not valid because:
+- %s
+
+Respond again with corrected JSON.
+
+JSON:
+`, strings.TrimRight(prompt, "\n"), schema, strings.TrimSpace(output), strings.Join(problems, "\n- "))
+}
+
+// decode extracts the JSON from the output, validates it against the
+// schema and decodes it into a T.
+func decode[T any](schema *Schema, output string) (T, []string) {
+	var value T
+
+	raw, err := llm.ExtractJSON(output)
+	if err != nil {
+		return value, []string{err.Error()}
+	}
+
+	// Validate the generic form first so the model gets every problem at
+	// once rather than only the first decoding error.
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return value, []string{"invalid JSON: " + err.Error()}
+	}
+	if problems := Validate(schema, generic); len(problems) > 0 {
+		return value, problems
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&value); err != nil {
+		return value, []string{err.Error()}
+	}
+	return value, nil
+}
@@ -0,0 +1,146 @@
+package structured
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Validate checks a decoded JSON value against the schema and returns a
+// description of every violation, each prefixed with its JSON path.
+func Validate(s *Schema, value any) []string {
+	return validate(s, value, "$", nil)
+}
+
+// validate appends the violations of value at path to problems.
+func validate(s *Schema, value any, path string, problems []string) []string {
+	if s == nil || (s.Type == "" && s.Enum == nil) {
+		return problems
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an object, got %s", path, describe(value)))
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+		for _, name := range s.propertyNames() {
+			v, ok := obj[name]
+			if !ok || (v == nil && !slices.Contains(s.Required, name)) {
+				continue
+			}
+			problems = validate(s.Properties[name], v, path+"."+name, problems)
+		}
+		if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			extra := []string{}
+			for name := range obj {
+				if _, ok := s.Properties[name]; !ok {
+					extra = append(extra, name)
+				}
+			}
+			sort.Strings(extra)
+			for _, name := range extra {
+				problems = append(problems, fmt.Sprintf("%s: unexpected field %q", path, name))
+			}
+		}
+		return problems
+
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an array, got %s", path, describe(value)))
+		}
+		for i, v := range arr {
+			problems = validate(s.Items, v, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+		return problems
+
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return append(problems, fmt.Sprintf("%s: expected an integer, got %s", path, describe(value)))
+		}
+		return problems
+
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return append(problems, fmt.Sprintf("%s: expected a number, got %s", path, describe(value)))
+		}
+		return problems
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(problems, fmt.Sprintf("%s: expected true or false, got %s", path, describe(value)))
+		}
+		return problems
+	}
+
+	// Everything else is a string.
+	str, ok := value.(string)
+	if !ok {
+		return append(problems, fmt.Sprintf("%s: expected a string, got %s", path, describe(value)))
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", path, str, strings.Join(s.Enum, ", ")))
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err == nil && !re.MatchString(str) {
+			problems = append(problems, fmt.Sprintf("%s: %q does not match the pattern %s", path, str, s.Pattern))
+		}
+	}
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %q is not an RFC 3339 date-time", path, str))
+		}
+	case "byte":
+		if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %q is not base64", path, str))
+		}
+	}
+	return problems
+}
+
+// propertyNames returns the property names in field order, falling back to
+// sorted order for schemas that were not derived from a struct.
+func (s *Schema) propertyNames() []string {
+	if len(s.order) == len(s.Properties) {
+		return s.order
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describe names the JSON type of a decoded value for error messages.
+func describe(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case float64:
+		return fmt.Sprintf("the number %g", v)
+	}
+	return fmt.Sprintf("%T", value)
+}
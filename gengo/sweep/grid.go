@@ -0,0 +1,159 @@
+package sweep
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+)
+
+// Param is a single CompletionRequest field set to a value.
+type Param struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// Axis is a CompletionRequest field, named by its JSON name, and the
+// values to sweep it over.
+type Axis struct {
+	Field  string
+	Values []string
+}
+
+// Grid is a set of axes. Every combination of their values is a cell.
+type Grid []Axis
+
+// ParseAxis parses field=values where values is either a numeric range
+// start:end:step, inclusive of end, or a comma separated list.
+//
+//	temperature=0.1:2.0:0.4
+//	max_tokens=30,110,190
+//	model=Camel-5B,Nous-Hermes-Llama2-13B
+func ParseAxis(s string) (Axis, error) {
+	field, values, ok := strings.Cut(s, "=")
+	if !ok || field == "" || values == "" {
+		return Axis{}, fmt.Errorf("expected field=values, got %q", s)
+	}
+	if _, err := fieldByName(&llm.CompletionRequest{}, field); err != nil {
+		return Axis{}, err
+	}
+
+	axis := Axis{Field: field}
+	if strings.Count(values, ":") == 2 && !strings.Contains(values, ",") {
+		nums, err := parseRange(values)
+		if err != nil {
+			return Axis{}, fmt.Errorf("%s: %w", field, err)
+		}
+		for _, n := range nums {
+			axis.Values = append(axis.Values, strconv.FormatFloat(n, 'f', -1, 64))
+		}
+		return axis, nil
+	}
+	for _, v := range strings.Split(values, ",") {
+		axis.Values = append(axis.Values, strings.TrimSpace(v))
+	}
+	return axis, nil
+}
+
+// parseRange expands start:end:step into its values.
+func parseRange(s string) ([]float64, error) {
+	parts := strings.Split(s, ":")
+	nums := make([]float64, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	start, end, step := nums[0], nums[1], nums[2]
+	if step <= 0 || end < start {
+		return nil, fmt.Errorf("invalid range %q", s)
+	}
+
+	// Compute each value from its index so that floating point error does
+	// not accumulate across the steps.
+	n := int(math.Floor((end-start)/step+1e-9)) + 1
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.Round((start+float64(i)*step)*1e9) / 1e9
+	}
+	return values, nil
+}
+
+// Cells returns every combination of the axis values, varying the last
+// axis fastest.
+func (g Grid) Cells() [][]Param {
+	cells := [][]Param{{}}
+	for _, axis := range g {
+		next := make([][]Param, 0, len(cells)*len(axis.Values))
+		for _, cell := range cells {
+			for _, v := range axis.Values {
+				params := append(append([]Param{}, cell...), Param{Field: axis.Field, Value: v})
+				next = append(next, params)
+			}
+		}
+		cells = next
+	}
+	return cells
+}
+
+// Apply returns a copy of the request with the params set.
+func Apply(request llm.CompletionRequest, params []Param) (llm.CompletionRequest, error) {
+	for _, p := range params {
+		field, err := fieldByName(&request, p.Field)
+		if err != nil {
+			return request, err
+		}
+		if err := setValue(field, p.Value); err != nil {
+			return request, fmt.Errorf("%s: %w", p.Field, err)
+		}
+	}
+	return request, nil
+}
+
+// fieldByName finds the scalar CompletionRequest field with the given JSON
+// name.
+func fieldByName(request *llm.CompletionRequest, name string) (reflect.Value, error) {
+	v := reflect.ValueOf(request).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag != name {
+			continue
+		}
+		switch t.Field(i).Type.Kind() {
+		case reflect.String, reflect.Int, reflect.Float64:
+			return v.Field(i), nil
+		}
+		return reflect.Value{}, fmt.Errorf("field %q cannot be swept", name)
+	}
+	return reflect.Value{}, fmt.Errorf("unknown completion request field %q", name)
+}
+
+// setValue parses s into the field.
+func setValue(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Int:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		if f != math.Trunc(f) {
+			return fmt.Errorf("%s is not an integer", s)
+		}
+		field.SetInt(int64(f))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	}
+	return nil
+}
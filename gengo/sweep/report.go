@@ -0,0 +1,132 @@
+package sweep
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable writes one row per cell with its metrics and first output.
+func WriteTable(w io.Writer, cells []Cell) error {
+	if len(cells) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	// Header.
+	header := []string{}
+	for _, p := range cells[0].Params {
+		header = append(header, p.Field)
+	}
+	header = append(header, "samples", "errors", "tokens", "latency_ms", "unique", "distinct_1", "distinct_2", "self_bleu", "similarity", "truncated", "example")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	// Rows.
+	for _, c := range cells {
+		row := []string{}
+		for _, p := range c.Params {
+			row = append(row, p.Value)
+		}
+		m := c.Metrics
+		row = append(row,
+			strconv.Itoa(m.Samples),
+			strconv.Itoa(m.Errors),
+			fmt.Sprintf("%.1f", m.AverageTokens),
+			fmt.Sprintf("%.0f", m.MeanLatencyMS),
+			fmt.Sprintf("%.2f", m.Unique),
+			fmt.Sprintf("%.2f", m.Distinct1),
+			fmt.Sprintf("%.2f", m.Distinct2),
+			fmt.Sprintf("%.2f", m.SelfBLEU),
+			similarity(m, 2),
+			fmt.Sprintf("%.2f", m.TruncationRate),
+			example(c),
+		)
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// example returns the first successful output of a cell, shortened to fit
+// on a table row.
+func example(c Cell) string {
+	for _, s := range c.Samples {
+		if s.err != nil {
+			continue
+		}
+		out := strings.Join(strings.Fields(s.Output), " ")
+		if r := []rune(out); len(r) > 60 {
+			out = string(r[:57]) + "..."
+		}
+		return strconv.Quote(out)
+	}
+	return ""
+}
+
+// WriteCSV writes one row per sample, with the cell's parameters and
+// metrics repeated on each row.
+func WriteCSV(w io.Writer, cells []Cell) error {
+	if len(cells) == 0 {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+
+	// Header.
+	header := []string{}
+	for _, p := range cells[0].Params {
+		header = append(header, p.Field)
+	}
+	header = append(header, "sample", "output", "tokens", "truncated", "latency_ms", "error", "unique", "distinct_1", "distinct_2", "self_bleu", "similarity", "truncation_rate")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	// Rows.
+	for _, c := range cells {
+		for i, s := range c.Samples {
+			row := []string{}
+			for _, p := range c.Params {
+				row = append(row, p.Value)
+			}
+			m := c.Metrics
+			row = append(row,
+				strconv.Itoa(i),
+				s.Output,
+				strconv.Itoa(s.Tokens),
+				strconv.FormatBool(s.Truncated),
+				strconv.FormatFloat(s.LatencyMS, 'f', 1, 64),
+				s.Error,
+				strconv.FormatFloat(m.Unique, 'f', 4, 64),
+				strconv.FormatFloat(m.Distinct1, 'f', 4, 64),
+				strconv.FormatFloat(m.Distinct2, 'f', 4, 64),
+				strconv.FormatFloat(m.SelfBLEU, 'f', 4, 64),
+				similarity(m, 4),
+				strconv.FormatFloat(m.TruncationRate, 'f', 4, 64),
+			)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// similarity formats the embedding similarity, which is blank when it was
+// not measured.
+func similarity(m Metrics, precision int) string {
+	if m.Similarity == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*m.Similarity, 'f', precision, 64)
+}
+
+// WriteJSON writes the cells as indented JSON.
+func WriteJSON(w io.Writer, cells []Cell) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cells)
+}
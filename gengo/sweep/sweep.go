@@ -0,0 +1,245 @@
+// Package sweep runs a prompt over a grid of completion parameters,
+// sampling each combination several times, and reports on the outputs.
+package sweep
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/metrics"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/tokens"
+)
+
+// Sample is a single completion generated for a cell.
+type Sample struct {
+	Output    string  `json:"output"`
+	Tokens    int     `json:"tokens"`
+	Truncated bool    `json:"truncated"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+
+	raw string
+	err error
+}
+
+// Metrics summarize the samples of a cell.
+type Metrics struct {
+	Samples       int     `json:"samples"`
+	Errors        int     `json:"errors"`
+	MeanLatencyMS float64 `json:"mean_latency_ms"`
+	metrics.Summary
+
+	// SimilarityError explains why the embedding similarity is missing.
+	SimilarityError string `json:"similarity_error,omitempty"`
+}
+
+// Cell is one combination of parameters and the samples generated for it.
+type Cell struct {
+	Params  []Param  `json:"params"`
+	Samples []Sample `json:"samples"`
+	Metrics Metrics  `json:"metrics"`
+}
+
+// Runner samples completions for every cell of a grid.
+type Runner struct {
+	Completer llm.Completer
+
+	// Samples is the number of completions generated per cell.
+	Samples int
+
+	// Concurrency is the number of requests in flight at once.
+	Concurrency int
+
+	// Interval is the minimum time between the start of two requests.
+	// Zero means no rate limit.
+	Interval time.Duration
+
+	// Stop tokens the outputs are truncated at, when the base request has
+	// none of its own.
+	Stop []string
+
+	// Embedder, if set, is used to measure how semantically similar the
+	// samples of each cell are.
+	Embedder embedding.Embedder
+}
+
+// job is a single sample to generate.
+type job struct {
+	cell    int
+	sample  int
+	request llm.CompletionRequest
+}
+
+// Run generates the samples for every cell of the grid, starting from the
+// base request. Failed requests are recorded on their sample rather than
+// stopping the sweep; Run only returns an error for an invalid grid or a
+// cancelled context.
+func (r *Runner) Run(ctx context.Context, base llm.CompletionRequest, grid Grid) ([]Cell, error) {
+	samples := r.Samples
+	if samples <= 0 {
+		samples = 1
+	}
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if base.Stop == nil {
+		base.Stop = r.Stop
+	}
+
+	// Build the cells and the jobs to fill them.
+	paramSets := grid.Cells()
+	cells := make([]Cell, len(paramSets))
+	jobs := make([]job, 0, len(paramSets)*samples)
+	for i, params := range paramSets {
+		request, err := Apply(base, params)
+		if err != nil {
+			return nil, err
+		}
+		cells[i] = Cell{Params: params, Samples: make([]Sample, samples)}
+		for s := 0; s < samples; s++ {
+			jobs = append(jobs, job{cell: i, sample: s, request: request})
+		}
+	}
+
+	// Work through the jobs with a bounded pool of workers. Every worker
+	// writes to its own slot so the results stay in order.
+	limit := limiter{interval: r.Interval}
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				if err := limit.wait(ctx); err != nil {
+					cells[j.cell].Samples[j.sample] = Sample{Error: err.Error(), err: err}
+					continue
+				}
+				cells[j.cell].Samples[j.sample] = r.sample(ctx, j.request)
+			}
+		}()
+	}
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range cells {
+		cells[i].Metrics = r.summarize(ctx, cells[i].Samples, base.Stop)
+	}
+	return cells, nil
+}
+
+// sample generates and times a single completion.
+func (r *Runner) sample(ctx context.Context, request llm.CompletionRequest) Sample {
+	start := time.Now()
+	response, err := r.Completer.Complete(ctx, request)
+	latency := time.Since(start)
+	if err == nil && len(response.Choices) == 0 {
+		err = llm.ErrNoChoices
+	}
+	if err != nil {
+		return Sample{Error: err.Error(), err: err, LatencyMS: milliseconds(latency)}
+	}
+
+	raw := response.Choices[0].Text
+	output := llm.TrimStop(raw, request.Stop)
+	return Sample{
+		Output:    output,
+		Tokens:    tokens.Count(output),
+		Truncated: metrics.TruncationRate([]string{raw}, request.Stop) > 0,
+		LatencyMS: milliseconds(latency),
+		raw:       raw,
+	}
+}
+
+// summarize computes the metrics over the successful samples.
+func (r *Runner) summarize(ctx context.Context, samples []Sample, stop []string) Metrics {
+	m := Metrics{Samples: len(samples)}
+	outputs := []string{}
+	raw := []string{}
+	for _, s := range samples {
+		if s.err != nil {
+			m.Errors++
+			continue
+		}
+		outputs = append(outputs, s.Output)
+		raw = append(raw, s.raw)
+		m.MeanLatencyMS += s.LatencyMS
+	}
+	if len(outputs) == 0 {
+		return m
+	}
+	m.MeanLatencyMS /= float64(len(outputs))
+
+	summary, err := metrics.Summarize(ctx, outputs, raw, stop, r.Embedder)
+	if err != nil {
+		m.SimilarityError = err.Error()
+	}
+	m.Summary = summary
+	return m
+}
+
+// FirstError returns the first error recorded on any sample, or nil.
+func FirstError(cells []Cell) error {
+	for _, c := range cells {
+		for _, s := range c.Samples {
+			if s.err != nil {
+				return s.err
+			}
+		}
+	}
+	return nil
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// limiter spaces out requests so that at most one starts every interval.
+type limiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+// wait blocks until the caller may start its request.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+
+	// Reserve the next free slot.
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+// Package tokens counts text in model tokens rather than words.
+package tokens
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/cohere-ai/tokenizer"
+)
+
+// Vocabulary is the prebuilt Cohere BPE vocabulary used for counting.
+const Vocabulary = "coheretext-50k"
+
+var (
+	loadOnce sync.Once
+	encoder  *tokenizer.Encoder
+)
+
+// defaultEncoder lazily loads the prebuilt tokenizer. It returns nil if
+// the vocabulary could not be loaded.
+func defaultEncoder() *tokenizer.Encoder {
+	loadOnce.Do(func() {
+		enc, err := tokenizer.NewFromPrebuilt(Vocabulary)
+		if err == nil {
+			encoder = enc
+		}
+	})
+	return encoder
+}
+
+// Count returns the number of tokens in text. If the tokenizer is not
+// available it falls back to an estimate of four tokens per three words.
+func Count(text string) int {
+	if enc := defaultEncoder(); enc != nil {
+		ids, _ := enc.Encode(text)
+		return len(ids)
+	}
+	return (len(strings.Fields(text))*4 + 2) / 3
+}
+
+// Boundaries returns the byte offset in text at which each token starts.
+// If the tokenizer is not available, or its tokens do not add up to the
+// text, each word is taken as a token.
+func Boundaries(text string) []int {
+	if enc := defaultEncoder(); enc != nil {
+		_, pieces := enc.Encode(text)
+		offsets := make([]int, len(pieces))
+		offset := 0
+		for i, p := range pieces {
+			offsets[i] = offset
+			offset += len(p)
+		}
+		if offset == len(text) {
+			return offsets
+		}
+	}
+
+	offsets := []int{}
+	inWord := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if !space && !inWord {
+			offsets = append(offsets, i)
+		}
+		inWord = !space
+	}
+	return offsets
+}
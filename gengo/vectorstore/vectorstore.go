@@ -0,0 +1,90 @@
+// Package vectorstore holds embedded chunks, along with where they came
+// from, and searches them by cosine similarity.
+package vectorstore
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+)
+
+// VectorizedChunk is a struct that holds a vectorized chunk.
+type VectorizedChunk struct {
+	document.Chunk
+	Vector []float64 `json:"vector"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It also reads the chunks of
+// older indexes, which held only the chunk's text as "chunk".
+func (c *VectorizedChunk) UnmarshalJSON(data []byte) error {
+	type plain VectorizedChunk
+	var v struct {
+		plain
+		Text string `json:"chunk"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = VectorizedChunk(v.plain)
+	if c.Content == "" {
+		c.Content = v.Text
+	}
+	return nil
+}
+
+// VectorizedChunks is a slice of vectorized chunks.
+type VectorizedChunks []VectorizedChunk
+
+// Result is a chunk returned by a search along with its similarity to
+// the query.
+type Result struct {
+	document.Chunk
+	Similarity float64 `json:"similarity"`
+}
+
+// Load reads vectorized chunks from a JSON file.
+func Load(path string) (VectorizedChunks, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var chunks VectorizedChunks
+	if err := json.NewDecoder(f).Decode(&chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+// Save writes the vectorized chunks to a JSON file.
+func (chunks VectorizedChunks) Save(path string) error {
+	outJSON, err := json.MarshalIndent(chunks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, outJSON, 0644)
+}
+
+// Search through the vectorized chunks to find the k chunks most similar
+// to the query embedding, most similar first.
+func (chunks VectorizedChunks) Search(query []float64, k int) ([]Result, error) {
+	results := make([]Result, 0, len(chunks))
+	for _, c := range chunks {
+		similarity, err := embedding.CosineSimilarity(c.Vector, query)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{Chunk: c.Chunk, Similarity: similarity})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+	if k > 0 && k < len(results) {
+		results = results[:k]
+	}
+	return results, nil
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
 )
 
 // Define the API details to access the LLM.
@@ -101,13 +103,14 @@ func main() {
 	}
 
 	// Prompt a Code Generation LLM.
+	categories := []string{"purchase", "chat", "return", "other"}
+	//categories := []string{"POS", "NEU", "NEG"}
 	request := CompletionRequest{
 		Prompt: string(prompt),
 		Model:  "WizardCoder",
 		Output: TypedOutput{
 			Type:       "categorical",
-			Categories: []string{"purchase", "chat", "return", "other"},
-			//Categories: []string{"POS", "NEU", "NEG"},
+			Categories: categories,
 		},
 	}
 	response, err := getCompletions(request)
@@ -126,6 +129,18 @@ func main() {
 	}
 	completion = strings.TrimSpace(completion)
 
+	// Don't just trust the server to honor the categories. Check the
+	// completion is one of them, forgiving casing and small typos, and
+	// fall back to the last category if it isn't.
+	label, err := llm.ParseOutput(llm.TypedOutput{
+		Type:       "categorical",
+		Categories: categories,
+		Fallback:   categories[len(categories)-1],
+	}, completion)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Print the autocompletion.
-	fmt.Println("\n" + string(prompt) + completion)
+	fmt.Println("\n" + string(prompt) + label.(string))
 }
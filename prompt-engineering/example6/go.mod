@@ -0,0 +1,7 @@
+module github.com/predictionguard/gophercon-gen-ai/prompt-engineering/example6
+
+go 1.21.1
+
+require github.com/predictionguard/gophercon-gen-ai/gengo v0.0.0
+
+replace github.com/predictionguard/gophercon-gen-ai/gengo => ../../gengo
@@ -2,11 +2,14 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
 )
 
 // Define the API details to access the LLM.
@@ -109,12 +112,30 @@ func main() {
 			//Factuality: true,
 		},
 	}
-	response, err := getCompletions(request)
-	if err != nil {
-		log.Fatal(err)
+
+	// Decide what to do when a guard check fails, rather than giving up.
+	// A toxic completion is regenerated at a higher temperature, a
+	// completion that might not be factual is shown with a warning.
+	var response *CompletionResults
+	for attempt := 1; ; attempt++ {
+		response, err = getCompletions(request)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = llm.ParseGuardStatus(response.Choices[0].Status).Err()
+		if errors.Is(err, llm.ErrToxicity) && attempt < 3 {
+			fmt.Println("Regenerating:", err)
+			request.Temperature += 0.3
+			continue
+		}
+		break
 	}
-	if response.Choices[0].Status != "success" {
-		log.Fatal(response.Choices[0].Status)
+	switch {
+	case errors.Is(err, llm.ErrFactuality):
+		fmt.Println("Warning:", err)
+	case err != nil:
+		fmt.Println(err)
+		os.Exit(1)
 	}
 
 	// Post process the completion. Given that we are using a system prompt,
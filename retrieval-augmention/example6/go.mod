@@ -3,15 +3,19 @@ module github.com/predictionguard/gophercon-gen-ai/retrieval-augmention/example6
 go 1.21.1
 
 require (
-	github.com/JohannesKaufmann/html-to-markdown v1.4.1
 	github.com/cohere-ai/cohere-go v0.2.0
+	github.com/predictionguard/gophercon-gen-ai/gengo v0.0.0
 )
 
 require (
-	github.com/PuerkitoBio/goquery v1.8.1 // indirect
-	github.com/andybalholm/cascadia v1.3.1 // indirect
+	github.com/JohannesKaufmann/html-to-markdown v1.4.1 // indirect
+	github.com/PuerkitoBio/goquery v1.9.2 // indirect
+	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/cohere-ai/tokenizer v1.1.1 // indirect
 	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/net v0.14.0 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/predictionguard/gophercon-gen-ai/gengo => ../../gengo
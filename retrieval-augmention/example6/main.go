@@ -2,18 +2,23 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log"
-	"math"
 	"net/http"
 	"os"
 	"strings"
 
-	md "github.com/JohannesKaufmann/html-to-markdown"
 	cohere "github.com/cohere-ai/cohere-go"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/crawl"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/document"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/embedding"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/ingest"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/llm"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/rag"
+	"github.com/predictionguard/gophercon-gen-ai/gengo/vectorstore"
 )
 
 // Define the API details to access the LLM.
@@ -122,127 +127,21 @@ func embed(message string, co *cohere.Client) ([]float64, error) {
 	return res.Embeddings[0], nil
 }
 
-// VectorizedChunk is a struct that holds a vectorized chunk.
-type VectorizedChunk struct {
-	Chunk  string    `json:"chunk"`
-	Vector []float64 `json:"vector"`
-}
-
-// VectorizedChunks is a slice of vectorized chunks.
-type VectorizedChunks []VectorizedChunk
-
-// cosineSimilarity calculates the cosine similarity between two vectors.
-func cosineSimilarity(a []float64, b []float64) (cosine float64, err error) {
-	count := 0
-	length_a := len(a)
-	length_b := len(b)
-	if length_a > length_b {
-		count = length_a
-	} else {
-		count = length_b
-	}
-	sumA := 0.0
-	s1 := 0.0
-	s2 := 0.0
-	for k := 0; k < count; k++ {
-		if k >= length_a {
-			s2 += math.Pow(b[k], 2)
-			continue
-		}
-		if k >= length_b {
-			s1 += math.Pow(a[k], 2)
-			continue
-		}
-		sumA += a[k] * b[k]
-		s1 += math.Pow(a[k], 2)
-		s2 += math.Pow(b[k], 2)
-	}
-	if s1 == 0 || s2 == 0 {
-		return 0.0, errors.New("vectors should not be null (all zeros)")
-	}
-	return sumA / (math.Sqrt(s1) * math.Sqrt(s2)), nil
-}
-
-// search through the vectorized chunks to find the most similar chunk.
-func search(chunks VectorizedChunks, embedding []float64) (string, error) {
-	outChunk := ""
-	var maxSimilarity float64 = 0.0
-	for _, c := range chunks {
-		distance, err := cosineSimilarity(c.Vector, embedding)
-		if err != nil {
-			return "", err
-		}
-		if distance > maxSimilarity {
-			outChunk = c.Chunk
-			maxSimilarity = distance
-		}
-	}
-	return outChunk, nil
-}
-
-// characterTextSplitter takes in a string and splits the string into
-// chunks of a given size (split on whitespace) with an overlap of a
-// given size of tokens (split on whitespace).
-func characterTextSplitter(text string, splitSize int, overlapSize int) []string {
-
-	// Create a slice to hold the chunks.
-	chunks := []string{}
-
-	// Split the text into tokens based on whitespace.
-	tokens := strings.Split(text, " ")
-
-	// Loop over the tokens creating chunks of size splitSize with an
-	// overlap of overlapSize.
-	for i := 0; i < len(tokens); i += splitSize - overlapSize {
-		end := i + splitSize - overlapSize
-		if end > len(tokens) {
-			end = len(tokens)
-		}
-		chunks = append(chunks, strings.Join(tokens[i:end], " "))
+// websiteDocuments crawls a website from the given page, following its
+// links and sitemap up to the given depth and number of pages.
+func websiteDocuments(website string, depth int, maxPages int) ([]document.Document, error) {
+	crawler := crawl.New()
+	crawler.MaxDepth = depth
+	crawler.MaxPages = maxPages
+	crawler.OnError = func(url string, err error) {
+		log.Printf("skipping %s: %v", url, err)
 	}
-	return chunks
+	return crawler.Crawl(context.Background(), website)
 }
 
-// websitechunks loads in a website and splits it into chunks with an
-// optional start string and end string.
-func websiteChunks(website string, start string, end string) ([]string, error) {
-
-	converter := md.NewConverter("", true, nil)
-
-	// Download the Go contribution guide.
-	res, err := http.Get(website)
-	if err != nil {
-		return nil, err
-	}
-	content, err := io.ReadAll(res.Body)
-	res.Body.Close()
-	if err != nil {
-		return nil, err
-	}
-	html := string(content)
-
-	// Convert the html to markdown for convenience.
-	markdown, err := converter.ConvertString(html)
-	if err != nil {
-		return nil, err
-	}
-
-	// Split the markdown string on any provided start and end strings.
-	if start != "" {
-		markdown_remaining := strings.Split(markdown, start)[1:]
-		markdown = strings.Join(markdown_remaining, "")
-	}
-	if end != "" {
-		markdown = strings.Split(markdown, end)[0]
-	}
-
-	// Split the text into reasonable size chunks with an overlap.
-	chunks := characterTextSplitter(markdown, 100, 10)
-	return chunks, nil
-}
-
-// getRAGAnswer gets a retrieval based answer.
-func getRAGAnswer(input string, chunks VectorizedChunks, co *cohere.Client) (string, error) {
+// getRAGAnswer gets a retrieval based answer, citing the chunk it came
+// from, or the refusal message when the answer is not grounded in it.
+func getRAGAnswer(input string, chunks vectorstore.VectorizedChunks, co *cohere.Client, grounder *rag.Grounder) (string, error) {
 
 	// Embed a question for the RAG answer.
 	embedding, err := embed(input, co)
@@ -251,15 +150,16 @@ func getRAGAnswer(input string, chunks VectorizedChunks, co *cohere.Client) (str
 	}
 
 	// Search for the relevant chunk.
-	chunk, err := search(chunks, embedding)
+	results, err := chunks.Search(embedding, 1)
 	if err != nil {
 		return "", err
 	}
+	chunk := results[0].Chunk
 
 	// Prompt with the Q&A template.
 	request := CompletionRequest{
 		Prompt: qAPromptTemplate(
-			chunk,
+			chunk.Content,
 			input,
 		),
 		Model: "Nous-Hermes-Llama2-13B",
@@ -280,7 +180,16 @@ func getRAGAnswer(input string, chunks VectorizedChunks, co *cohere.Client) (str
 	}
 	completion = strings.TrimSpace(completion)
 
-	return completion, nil
+	// Check the answer is supported by the chunk.
+	grounding, err := grounder.Check(context.Background(), chunk.Content, input, completion)
+	if err != nil {
+		return "", err
+	}
+	if !grounding.Grounded || rag.IsFallback(completion) {
+		return rag.Fallback, nil
+	}
+
+	return completion + "\n\nSource: " + chunk.Citation(), nil
 }
 
 // chatContext is a struct that holds a chat context.
@@ -333,11 +242,23 @@ You are a helpful and kind chat assistant. Respond to the below user input based
 
 func main() {
 
-	// Get the website from the command line arg.
-	website := os.Args[1]
+	// Get the website to crawl from the command line.
+	depth := flag.Int("depth", crawl.DefaultMaxDepth, "number of links to follow from the website")
+	maxPages := flag.Int("max-pages", 50, "most pages to download")
+	index := flag.String("index", "chunks.json", "file the embedded chunks are kept in between runs")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: example6 [flags] website")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	website := flag.Arg(0)
 
-	// Download the Go contribution guide in chunks.
-	chunks, err := websiteChunks(website, "", "")
+	// Crawl the website.
+	docs, err := websiteDocuments(website, *depth, *maxPages)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -352,49 +273,27 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	embedder, err := embedding.NewCohere(apiKey)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Embed the website chunks.
-	vectorizedChunks := VectorizedChunks{}
-	if len(chunks) > 20 {
-
-		// Batch requests to cohere in batches of 20 or less chunks.
-		for i := 0; i < len(chunks); i += 20 {
-			end := i + 20
-			if end > len(chunks) {
-				end = len(chunks)
-			}
-			res, err := co.Embed(cohere.EmbedOptions{
-				Model: "embed-english-light-v2.0",
-				Texts: chunks[i:end],
-			})
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			// Add the vectorized chunk to the vectorized chunks.
-			for j, chunk := range chunks[i:end] {
-				vectorizedChunks = append(vectorizedChunks, VectorizedChunk{
-					Chunk:  chunk,
-					Vector: res.Embeddings[j],
-				})
-			}
-		}
-	} else {
-		res, err := co.Embed(cohere.EmbedOptions{
-			Model: "embed-english-light-v2.0",
-			Texts: chunks,
-		})
-		if err != nil {
-			log.Fatal(err)
-		}
-		for j, chunk := range chunks {
-			vectorizedChunks = append(vectorizedChunks, VectorizedChunk{
-				Chunk:  chunk,
-				Vector: res.Embeddings[j],
-			})
-		}
+	// Split the pages into chunks and embed the chunks of the pages that
+	// changed since the last run.
+	indexer := ingest.Indexer{Path: *index, Embedder: embedder}
+	stats, err := indexer.Update(context.Background(), docs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("indexed %d chunks from %d pages, embedding %d", stats.Chunks, stats.Documents, stats.Embedded)
+	vectorizedChunks, err := vectorstore.Load(*index)
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	// Check answers against the chunks they came from.
+	grounder := rag.NewGrounder(embedder)
+
 	// Start a cycle of listening for questions and responding to the questions.
 	fmt.Println("")
 	convo := chatContexts{}
@@ -432,11 +331,23 @@ Is the user asking an informational question or just wanting to chat? Answer "ye
 			log.Fatal(err)
 		}
 
+		// Check the answer locally, treating anything that isn't
+		// recognizably "yes" or "no" as chat.
+		value, err := llm.ParseOutput(llm.TypedOutput{
+			Type:       llm.OutputCategorical,
+			Categories: []string{"yes", "no"},
+			Fallback:   "no",
+		}, response.Choices[0].Text)
+		if err != nil {
+			log.Fatal(err)
+		}
+		informational := value == "yes"
+
 		// Handle the input accordingly.
 		var completion string
-		switch response.Choices[0].Text {
-		case "yes":
-			completion, err = getRAGAnswer(input, vectorizedChunks, co)
+		switch {
+		case informational:
+			completion, err = getRAGAnswer(input, vectorizedChunks, co, grounder)
 			if err != nil {
 				log.Fatal(err)
 			}